@@ -1,16 +1,29 @@
 package day02
 
 import (
+	"fmt"
 	"io"
 	"runtime"
 
 	"github.com/MKuranowski/AdventOfCode2019/intcode"
 )
 
+// NounVerb returns a patch setting the inputs of the program
+func NounVerb(noun, verb int) intcode.Patch {
+	return intcode.Patch{
+		Name:    fmt.Sprintf("%02d%02d", noun, verb),
+		Entries: []intcode.PatchEntry{{Addr: 1, Value: noun}, {Addr: 2, Value: verb}},
+	}
+}
+
+// Alarm1202 restores the program to the "1202 program alarm" state
+var Alarm1202 = NounVerb(12, 2)
+
 func SolveA(r io.Reader) any {
-	i := intcode.NewInterpreter(r)
-	i.Memory[1] = 12
-	i.Memory[2] = 2
+	i, err := intcode.NewInterpreterPatched(r, Alarm1202)
+	if err != nil {
+		panic(err)
+	}
 	i.ExecAll()
 	return i.Memory[0]
 }
@@ -28,8 +41,9 @@ func SolveB(r io.Reader) any {
 		go func() {
 			for input := range ins {
 				i := baseInterpreter.Clone()
-				i.Memory[1] = input.Noun
-				i.Memory[2] = input.Verb
+				if err := i.ApplyPatch(NounVerb(input.Noun, input.Verb)); err != nil {
+					panic(err)
+				}
 				i.ExecAll()
 				if i.Memory[0] == 19690720 {
 					results <- 100*input.Noun + input.Verb
@@ -96,7 +96,9 @@ func SolveA(r io.Reader) any {
 type Arcade struct {
 	S            Screen
 	BallColumn   int
+	BallRow      int
 	PaddleColumn int
+	PaddleRow    int
 	Score        int
 
 	Draw     <-chan int
@@ -124,6 +126,11 @@ func (a *Arcade) continueDraw(col int) {
 		return
 	}
 
+	a.draw(col, row, data)
+}
+
+// draw executes a single draw command of the game
+func (a *Arcade) draw(col, row, data int) {
 	// Special case for the score
 	if col == -1 {
 		a.Score = data
@@ -149,21 +156,34 @@ func (a *Arcade) continueDraw(col int) {
 	a.S[row][col] = tile
 
 	if tile == TileBall {
-		a.BallColumn = col
+		a.BallColumn, a.BallRow = col, row
 	} else if tile == TilePaddle {
-		a.PaddleColumn = col
+		a.PaddleColumn, a.PaddleRow = col, row
+	}
+}
+
+// Tile returns the tile at the provided position, treating everything outside of the screen as walls
+func (a *Arcade) Tile(col, row int) TileType {
+	if row < 0 || row >= len(a.S) || col < 0 || col >= len(a.S[row]) {
+		return TileWall
 	}
+	return a.S[row][col]
 }
 
-func (a *Arcade) getJoystickInput() int {
-	if a.PaddleColumn > a.BallColumn {
-		return -1
-	} else if a.PaddleColumn < a.BallColumn {
-		return 1
+// Blocks returns the amount of blocks left on the screen
+func (a *Arcade) Blocks() (blocks int) {
+	for _, row := range a.S {
+		for _, tile := range row {
+			if tile == TileBlock {
+				blocks++
+			}
+		}
 	}
-	return 0
+	return
 }
 
+func (a *Arcade) getJoystickInput() int { return FollowBall{}.Joystick(a) }
+
 func (a *Arcade) Run() {
 	for {
 		select {
@@ -179,7 +199,17 @@ func (a *Arcade) Run() {
 	}
 }
 
-func SolveB(r io.Reader) any {
+// FreePlay "hacks the amount of coins", so that the game can be played
+var FreePlay = intcode.Patch{
+	Name:    "free-play",
+	Entries: []intcode.PatchEntry{{Addr: 0, Value: 2, Check: true, Original: 1}},
+}
+
+func SolveB(r io.Reader) any { return SolveBTraced(r, nil) }
+
+// SolveBTraced plays the game just like SolveB,
+// reporting every instruction executed by the game to t.
+func SolveBTraced(r io.Reader, t intcode.Tracer) any {
 	// Prepare the interpreter
 	i := intcode.NewInterpreterNewIO(r)
 	a := Arcade{
@@ -188,11 +218,12 @@ func SolveB(r io.Reader) any {
 		Halt:     i.Halted,
 	}
 
-	// "Hack the amount of coins"
-	i.Memory[0] = 2
+	if err := i.ApplyPatch(FreePlay); err != nil {
+		panic(err)
+	}
 
 	// Launch the interpreter and the screen
-	go i.ExecAll()
+	go i.ExecAllTraced(t)
 	a.Run()
 	close(i.Input)
 
@@ -0,0 +1,208 @@
+package day13
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Key uint8
+
+const (
+	KeyLeft = Key(iota)
+	KeyRight
+	KeyAutopilot
+	KeyFaster
+	KeySlower
+	KeyQuit
+)
+
+// ReadKeys decodes key presses from a terminal in raw mode. Arrow keys (or h and l)
+// move the joystick, a toggles the autopilot, + and - change the frame rate, and q or Ctrl-C quit.
+// The channel is closed once r is exhausted.
+func ReadKeys(r io.Reader) <-chan Key {
+	keys := make(chan Key, 16)
+
+	go func() {
+		defer close(keys)
+		br := bufio.NewReader(r)
+
+		for {
+			b, err := br.ReadByte()
+			if err != nil {
+				return
+			}
+
+			switch b {
+			case '\x1b':
+				// Arrow keys are sent as ESC [ C (right) and ESC [ D (left)
+				if next, err := br.ReadByte(); err != nil || next != '[' {
+					continue
+				}
+				switch arrow, _ := br.ReadByte(); arrow {
+				case 'C':
+					keys <- KeyRight
+				case 'D':
+					keys <- KeyLeft
+				}
+			case 'h':
+				keys <- KeyLeft
+			case 'l':
+				keys <- KeyRight
+			case 'a':
+				keys <- KeyAutopilot
+			case '+', '=':
+				keys <- KeyFaster
+			case '-':
+				keys <- KeySlower
+			case 'q', '\x03':
+				keys <- KeyQuit
+			}
+		}
+	}()
+
+	return keys
+}
+
+// Player renders the Arcade live on an ANSI terminal after every joystick input,
+// and drives the joystick with the keyboard or the built-in autopilot.
+type Player struct {
+	Arcade
+
+	W          io.Writer
+	Keys       <-chan Key
+	FrameDelay time.Duration // Time between frames, 0 renders frames as fast as possible
+	Autopilot  bool
+
+	direction int  // Joystick position for the next frame
+	quit      bool // Set after the player has asked to quit
+}
+
+// readKeys handles all pending key presses
+func (p *Player) readKeys() {
+	for {
+		select {
+		case k, ok := <-p.Keys:
+			if !ok {
+				p.Keys = nil
+				return
+			}
+			p.handleKey(k)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Player) handleKey(k Key) {
+	switch k {
+	case KeyLeft:
+		p.direction = -1
+	case KeyRight:
+		p.direction = 1
+	case KeyAutopilot:
+		p.Autopilot = !p.Autopilot
+	case KeyFaster:
+		p.FrameDelay /= 2
+	case KeySlower:
+		if p.FrameDelay == 0 {
+			p.FrameDelay = time.Millisecond
+		}
+		p.FrameDelay *= 2
+	case KeyQuit:
+		p.quit = true
+	}
+}
+
+func (p *Player) joystick() int {
+	if p.Autopilot {
+		return p.getJoystickInput()
+	}
+	return p.direction
+}
+
+// Render draws the current frame. Lines end with "\r\n", as the terminal is expected to be in raw mode.
+func (p *Player) Render() {
+	b := &strings.Builder{}
+	b.WriteString("\x1b[H") // Move the cursor to the top-left corner
+
+	mode := "manual"
+	if p.Autopilot {
+		mode = "autopilot"
+	}
+	fps := "max"
+	if p.FrameDelay > 0 {
+		fps = fmt.Sprintf("%.1f", float64(time.Second)/float64(p.FrameDelay))
+	}
+	fmt.Fprintf(b, "Score: %-8d %-9s  fps: %-6s\x1b[K\r\n", p.Score, mode, fps)
+
+	for _, row := range p.S {
+		for _, tile := range row {
+			switch tile {
+			case TileBlock:
+				b.WriteString("\x1b[33mx\x1b[0m")
+			case TileBall:
+				b.WriteString("\x1b[1;36mo\x1b[0m")
+			case TilePaddle:
+				b.WriteString("\x1b[1;32m-\x1b[0m")
+			default:
+				b.WriteByte(tile.AsChar())
+			}
+		}
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString("←/→ move  a autopilot  +/- speed  q quit\x1b[K\r\n")
+
+	io.WriteString(p.W, b.String())
+}
+
+// Play runs the game until it halts, or the player quits. Returns false if the player quit.
+func (p *Player) Play() (finished bool) {
+	io.WriteString(p.W, "\x1b[2J\x1b[?25l") // Clear the screen and hide the cursor
+	defer io.WriteString(p.W, "\x1b[?25h")
+
+	for {
+		p.readKeys()
+		if p.quit {
+			return false
+		}
+
+		select {
+		case <-p.Halt:
+			p.Render()
+			return true
+		case col, ok := <-p.Draw:
+			if !ok {
+				p.Render()
+				return true
+			}
+			p.continueDraw(col)
+		case p.Joystick <- p.joystick():
+			p.direction = 0
+			p.Render()
+			p.wait()
+		}
+	}
+}
+
+// wait sleeps until the next frame, handling keys pressed in the meantime
+func (p *Player) wait() {
+	deadline := time.After(p.FrameDelay)
+	for {
+		select {
+		case <-deadline:
+			return
+		case k, ok := <-p.Keys:
+			if !ok {
+				p.Keys = nil
+				continue
+			}
+			p.handleKey(k)
+			if p.quit {
+				return
+			}
+		}
+	}
+}
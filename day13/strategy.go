@@ -0,0 +1,128 @@
+package day13
+
+import (
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+)
+
+// JoystickStrategy decides the position of the joystick (-1, 0 or 1)
+// every time the game asks for it, that is once per frame.
+type JoystickStrategy interface {
+	Joystick(a *Arcade) int
+}
+
+// JoystickFunc allows using ordinary functions as JoystickStrategies
+type JoystickFunc func(a *Arcade) int
+
+func (f JoystickFunc) Joystick(a *Arcade) int { return f(a) }
+
+// steer returns the joystick position moving the paddle towards the target column
+func steer(paddle, target int) int {
+	if paddle > target {
+		return -1
+	} else if paddle < target {
+		return 1
+	}
+	return 0
+}
+
+// FollowBall keeps the paddle right below the ball
+type FollowBall struct{}
+
+func (FollowBall) Joystick(a *Arcade) int { return steer(a.PaddleColumn, a.BallColumn) }
+
+// PredictTrajectory moves the paddle towards the column where the falling ball
+// is going to reach the paddle's row. Only bounces off walls are predicted.
+// While the ball is going up, the paddle follows it.
+type PredictTrajectory struct {
+	prevColumn, prevRow int
+	seen                bool
+}
+
+func (p *PredictTrajectory) Joystick(a *Arcade) int {
+	dx, dy := a.BallColumn-p.prevColumn, a.BallRow-p.prevRow
+	known := p.seen
+	p.prevColumn, p.prevRow, p.seen = a.BallColumn, a.BallRow, true
+
+	if !known || dy <= 0 || a.BallRow >= a.PaddleRow {
+		return steer(a.PaddleColumn, a.BallColumn)
+	}
+	return steer(a.PaddleColumn, p.landingColumn(a, dx))
+}
+
+// landingColumn simulates the ball moving diagonally down until it's right above the paddle
+func (p *PredictTrajectory) landingColumn(a *Arcade, dx int) int {
+	col := a.BallColumn
+	for row := a.BallRow; row < a.PaddleRow-1; row++ {
+		if a.Tile(col+dx, row) == TileWall {
+			dx = -dx
+		}
+		col += dx
+	}
+	return col
+}
+
+// ReplayedInputs moves the joystick just like a previously recorded game,
+// keeping it in the neutral position once the recording runs out.
+type ReplayedInputs struct {
+	Inputs []int
+	next   int
+}
+
+// NewReplayedInputs takes all inputs from a transcript of a game
+func NewReplayedInputs(t intcode.Transcript) *ReplayedInputs {
+	r := &ReplayedInputs{}
+	for _, e := range t {
+		if e.Kind == intcode.EventInput {
+			r.Inputs = append(r.Inputs, e.Value)
+		}
+	}
+	return r
+}
+
+func (r *ReplayedInputs) Joystick(*Arcade) int {
+	if r.next >= len(r.Inputs) {
+		return 0
+	}
+	r.next++
+	return r.Inputs[r.next-1]
+}
+
+// HeadlessResult describes a game played by PlayHeadless
+type HeadlessResult struct {
+	Score           int
+	BlocksRemaining int
+	Frames          int // Amount of joystick inputs
+	Steps           int // Amount of executed instructions
+	Halted          bool
+}
+
+// PlayHeadless plays the game (which needs to be patched with FreePlay) without a screen,
+// until it halts or maxFrames joystick inputs were given (0 for no limit).
+func PlayHeadless(game *intcode.SyncInterpreter, s JoystickStrategy, maxFrames int) HeadlessResult {
+	a := &Arcade{}
+	r := HeadlessResult{}
+
+	for {
+		state := game.ExecAll()
+		for game.Output.Len() >= 3 {
+			col := game.Output.PopFront()
+			row := game.Output.PopFront()
+			a.draw(col, row, game.Output.PopFront())
+		}
+
+		if state == intcode.SyncExecutionStateHalted {
+			r.Halted = true
+			break
+		} else if maxFrames > 0 && r.Frames >= maxFrames {
+			break
+		}
+
+		game.Input.PushBack(s.Joystick(a))
+		r.Frames++
+	}
+
+	r.Score = a.Score
+	r.BlocksRemaining = a.Blocks()
+	r.Steps = game.Steps
+	return r
+}
@@ -0,0 +1,259 @@
+package day15
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/deque"
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+var ErrInvalidMap = errors.New("invalid map")
+
+var directions = []Decision{DecisionNorth, DecisionSouth, DecisionWest, DecisionEast}
+
+// IsOpen returns true if the droid can move onto the tile
+func (t MapTile) IsOpen() bool { return t == MapTileCorridor || t == MapTileOxygen }
+
+// sortPoints sorts points from the top row to the bottom one (as shown by ShowMap), left to right
+func sortPoints(points []Point) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y > points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+}
+
+// openPoints returns all open tiles of the map, sorted
+func openPoints(m map[Point]MapTile) (points []Point) {
+	for p, tile := range m {
+		if tile.IsOpen() {
+			points = append(points, p)
+		}
+	}
+	sortPoints(points)
+	return
+}
+
+// ReadMapText reads a map in the format written by ShowMap.
+// The start ('x') is at (0, 0), and needs to be present on the map.
+func ReadMapText(r io.Reader) (m map[Point]MapTile, oxygen Point, err error) {
+	type char struct {
+		x, row int
+		c      byte
+	}
+	var chars []char
+	start, startFound := char{}, false
+	rows := 0
+
+	for row, line := range input.ReadLines(r) {
+		rows++
+		for x := 0; x < len(line); x++ {
+			c := char{x, row, line[x]}
+			switch c.c {
+			case ' ':
+				continue
+			case 'x':
+				start, startFound = c, true
+			case '#', '.', '*':
+			default:
+				return nil, oxygen, fmt.Errorf("%w: unexpected character %q at line %d", ErrInvalidMap, c.c, row+1)
+			}
+			chars = append(chars, c)
+		}
+	}
+
+	if !startFound {
+		return nil, oxygen, fmt.Errorf("%w: no start ('x') on the map", ErrInvalidMap)
+	}
+
+	m = make(map[Point]MapTile, len(chars))
+	for _, c := range chars {
+		p := Point{c.x - start.x, start.row - c.row}
+		switch c.c {
+		case '#':
+			m[p] = MapTileWall
+		case '.', 'x':
+			m[p] = MapTileCorridor
+		case '*':
+			m[p] = MapTileOxygen
+			oxygen = p
+		}
+	}
+	return m, oxygen, nil
+}
+
+type mapJSON struct {
+	Oxygen    *Point  `json:"oxygen,omitempty"`
+	Corridors []Point `json:"corridors"`
+	Walls     []Point `json:"walls"`
+}
+
+// WriteMapJSON writes the map as a JSON object with sorted lists of corridors and walls,
+// and the position of the oxygen system (if it's known).
+func WriteMapJSON(m map[Point]MapTile, w io.Writer) error {
+	j := mapJSON{Corridors: []Point{}, Walls: []Point{}}
+	for p, tile := range m {
+		switch tile {
+		case MapTileWall:
+			j.Walls = append(j.Walls, p)
+		case MapTileCorridor:
+			j.Corridors = append(j.Corridors, p)
+		case MapTileOxygen:
+			oxygen := p
+			j.Oxygen = &oxygen
+		}
+	}
+	sortPoints(j.Corridors)
+	sortPoints(j.Walls)
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(j)
+}
+
+// ReadMapJSON reads a map written by WriteMapJSON
+func ReadMapJSON(r io.Reader) (m map[Point]MapTile, oxygen Point, err error) {
+	j := mapJSON{}
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return nil, oxygen, fmt.Errorf("%w: %v", ErrInvalidMap, err)
+	}
+
+	m = make(map[Point]MapTile, len(j.Corridors)+len(j.Walls)+1)
+	for _, p := range j.Corridors {
+		m[p] = MapTileCorridor
+	}
+	for _, p := range j.Walls {
+		m[p] = MapTileWall
+	}
+	if j.Oxygen != nil {
+		oxygen = *j.Oxygen
+		m[oxygen] = MapTileOxygen
+	}
+	return m, oxygen, nil
+}
+
+// ReadMap reads a map written by ShowMap or WriteMapJSON, detecting the format automatically
+func ReadMap(r io.Reader) (m map[Point]MapTile, oxygen Point, err error) {
+	br := bufio.NewReader(r)
+	if first, err := br.Peek(1); err == nil && first[0] == '{' {
+		return ReadMapJSON(br)
+	}
+	return ReadMapText(br)
+}
+
+// DistanceField returns the amount of moves needed to reach every tile reachable from the provided point
+func DistanceField(m map[Point]MapTile, from Point) map[Point]int {
+	dist := map[Point]int{from: 0}
+	q := deque.NewDeque[Point]()
+	q.PushBack(from)
+
+	for q.Len() > 0 {
+		p := q.PopFront()
+		for _, d := range directions {
+			next := p.AfterDecision(d)
+			if _, visited := dist[next]; visited || !m[next].IsOpen() {
+				continue
+			}
+			dist[next] = dist[p] + 1
+			q.PushBack(next)
+		}
+	}
+
+	return dist
+}
+
+// Farthest returns the point with the greatest distance in a distance field.
+// Ties are broken by the order of points on the map, top to bottom and left to right.
+func Farthest(dist map[Point]int) (farthest Point, distance int) {
+	points := make([]Point, 0, len(dist))
+	for p := range dist {
+		points = append(points, p)
+	}
+	sortPoints(points)
+
+	distance = -1
+	for _, p := range points {
+		if dist[p] > distance {
+			farthest, distance = p, dist[p]
+		}
+	}
+	return
+}
+
+// DeadEnds returns all open tiles with exactly one open neighbor
+func DeadEnds(m map[Point]MapTile) (deadEnds []Point) {
+	for _, p := range openPoints(m) {
+		neighbors := 0
+		for _, d := range directions {
+			if m[p.AfterDecision(d)].IsOpen() {
+				neighbors++
+			}
+		}
+		if neighbors == 1 {
+			deadEnds = append(deadEnds, p)
+		}
+	}
+	return
+}
+
+// ArticulationPoints returns all open tiles which would split the maze into
+// disconnected parts if they were blocked.
+func ArticulationPoints(m map[Point]MapTile) (points []Point) {
+	discovered := make(map[Point]int)
+	low := make(map[Point]int)
+	isArticulation := make(map[Point]bool)
+
+	// Tarjan's algorithm - low[p] is the earliest discovered tile reachable from the
+	// subtree of p with at most one back edge
+	var visit func(p, parent Point, root bool)
+	visit = func(p, parent Point, root bool) {
+		discovered[p] = len(discovered) + 1
+		low[p] = discovered[p]
+		children := 0
+
+		for _, d := range directions {
+			next := p.AfterDecision(d)
+			if !m[next].IsOpen() || (!root && next == parent) {
+				continue
+			}
+
+			if _, seen := discovered[next]; seen {
+				if discovered[next] < low[p] {
+					low[p] = discovered[next]
+				}
+				continue
+			}
+
+			children++
+			visit(next, p, false)
+			if low[next] < low[p] {
+				low[p] = low[next]
+			}
+			if !root && low[next] >= discovered[p] {
+				isArticulation[p] = true
+			}
+		}
+
+		if root && children > 1 {
+			isArticulation[p] = true
+		}
+	}
+
+	for _, p := range openPoints(m) {
+		if _, seen := discovered[p]; !seen {
+			visit(p, p, true)
+		}
+	}
+
+	for p := range isArticulation {
+		points = append(points, p)
+	}
+	sortPoints(points)
+	return
+}
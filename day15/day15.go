@@ -9,8 +9,6 @@ import (
 	"github.com/MKuranowski/AdventOfCode2019/intcode"
 	"github.com/MKuranowski/AdventOfCode2019/util/gheap"
 	"github.com/MKuranowski/AdventOfCode2019/util/intmath"
-	"github.com/MKuranowski/AdventOfCode2019/util/maps2"
-	"golang.org/x/exp/maps"
 )
 
 type MapTile uint8
@@ -23,7 +21,8 @@ const (
 )
 
 type Point struct {
-	X, Y int
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 func (p Point) AfterDecision(d Decision) Point {
@@ -57,6 +56,22 @@ const (
 	DecisionEast
 )
 
+// Opposite returns the decision undoing a move
+func (d Decision) Opposite() Decision {
+	switch d {
+	case DecisionNorth:
+		return DecisionSouth
+	case DecisionSouth:
+		return DecisionNorth
+	case DecisionWest:
+		return DecisionEast
+	case DecisionEast:
+		return DecisionWest
+	default:
+		panic("invalid decision to reverse")
+	}
+}
+
 type Decider interface {
 	Decide(ControllerState) Decision
 }
@@ -65,17 +80,22 @@ type Controller struct {
 	ControllerState
 	I *intcode.Interpreter
 	D Decider
+	T intcode.Tracer // Optional
 }
 
 func (c *Controller) Run() {
 	if c.Map == nil {
 		c.Map = make(map[Point]MapTile)
 	}
+	if c.Map[c.Pos] == MapTileUnknown {
+		// The droid starts on an open tile
+		c.Map[c.Pos] = MapTileCorridor
+	}
 
 	// Launch the interpreter
 	c.I.Input = make(chan int)
 	c.I.Output = make(chan int)
-	go c.I.ExecAll()
+	go c.I.ExecAllTraced(c.T)
 
 	for {
 		// Get a decision from the Decider
@@ -106,8 +126,16 @@ func (c *Controller) Run() {
 	}
 }
 
+// RandomWalker does the provided amount of random moves.
+// Moves are taken from Rand, or from the global math/rand source if Rand is nil.
 type RandomWalker struct {
 	Steps int
+	Rand  *rand.Rand
+}
+
+// NewSeededRandomWalker creates a RandomWalker doing the same moves for the same seed
+func NewSeededRandomWalker(steps int, seed int64) *RandomWalker {
+	return &RandomWalker{Steps: steps, Rand: rand.New(rand.NewSource(seed))}
 }
 
 func (w *RandomWalker) Decide(ControllerState) Decision {
@@ -115,9 +143,44 @@ func (w *RandomWalker) Decide(ControllerState) Decision {
 		return DecisionHalt
 	}
 	w.Steps--
+	if w.Rand != nil {
+		return Decision(1 + w.Rand.Intn(4))
+	}
 	return Decision(1 + rand.Intn(4))
 }
 
+// DFSExplorer maps out the whole maze with a depth-first search, returning to previous
+// tiles by reversing its moves. It halts once every tile reachable from the start is known.
+type DFSExplorer struct {
+	path    []Decision // Moves leading from the start to the current position
+	last    Decision   // Last forward move, or DecisionHalt after backtracking
+	lastPos Point      // Position before the last forward move
+}
+
+func (e *DFSExplorer) Decide(s ControllerState) Decision {
+	// Remember the last move, if the droid didn't hit a wall
+	if e.last != DecisionHalt && s.Pos != e.lastPos {
+		e.path = append(e.path, e.last)
+	}
+
+	// Try to explore an unknown neighbor
+	for _, d := range directions {
+		if s.Map[s.Pos.AfterDecision(d)] == MapTileUnknown {
+			e.last, e.lastPos = d, s.Pos
+			return d
+		}
+	}
+
+	// Every neighbor is known - go back
+	e.last = DecisionHalt
+	if len(e.path) == 0 {
+		return DecisionHalt
+	}
+	back := e.path[len(e.path)-1].Opposite()
+	e.path = e.path[:len(e.path)-1]
+	return back
+}
+
 func ShowMap(m map[Point]MapTile, on io.Writer) {
 	// Find the bounds
 	minX, maxX := math.MaxInt, math.MinInt
@@ -214,48 +277,84 @@ func ShortestPath(m map[Point]MapTile, from Point, to Point) []Point {
 	panic("no path found")
 }
 
-func GetMap(r io.Reader) (m map[Point]MapTile, oxygen Point) {
-	i := intcode.NewInterpreter(r)
+// GetMap maps out the whole maze with a DFSExplorer
+func GetMap(r io.Reader) (m map[Point]MapTile, oxygen Point) { return GetMapTraced(r, nil) }
 
-	// Do a random walk of 1 million steps to map out the maze.
-	// That's a pretty stupid strategy, but only takes ~2 seconds and works, lol.
-	d := &RandomWalker{1_000_000}
-	c := &Controller{I: i.Clone(), D: d}
-	c.Run()
+// GetMapTraced maps out the maze just like GetMap,
+// reporting every instruction executed by the droid to t.
+func GetMapTraced(r io.Reader, t intcode.Tracer) (m map[Point]MapTile, oxygen Point) {
+	return ExploreMap(r, &DFSExplorer{}, t)
+}
 
+// ExploreMap maps out the maze by moving the droid as decided by d.
+// t is optional, and receives every instruction executed by the droid.
+func ExploreMap(r io.Reader, d Decider, t intcode.Tracer) (m map[Point]MapTile, oxygen Point) {
+	c := &Controller{I: intcode.NewInterpreter(r), D: d, T: t}
+	c.Run()
 	return c.Map, c.Oxygen
 }
 
-func SolveA(r io.Reader) any {
-	m, oxygen := GetMap(r)
+// NewSearch prepares a search exploring the maze with cloned droids,
+// where the state of each node is the position of the droid.
+func NewSearch() *intcode.Search[Point] {
+	return &intcode.Search[Point]{
+		Strategy: intcode.SearchBFS,
+		Moves: func(*intcode.SearchNode[Point]) [][]int {
+			return [][]int{{int(DecisionNorth)}, {int(DecisionSouth)}, {int(DecisionWest)}, {int(DecisionEast)}}
+		},
+		Update: func(parent, child *intcode.SearchNode[Point]) bool {
+			if child.Output[0] == 0 {
+				// Droid has hit a wall
+				return false
+			}
+			child.State = parent.State.AfterDecision(Decision(child.Move[0]))
+			return true
+		},
+		Goal: func(n *intcode.SearchNode[Point]) bool {
+			return len(n.Output) > 0 && n.Output[0] == 2
+		},
+		Key: func(n *intcode.SearchNode[Point]) any { return n.State },
+	}
+}
+
+// SearchOxygen returns the node with the droid standing on the oxygen system
+func SearchOxygen(droid *intcode.SyncInterpreter) *intcode.SearchNode[Point] {
+	s := NewSearch()
+	s.MaxGoals = 1
+	result := s.Run(droid, Point{0, 0})
+	if len(result.Goals) == 0 {
+		panic("oxygen system not found")
+	}
+	return result.Goals[0]
+}
+
+// SolveASearch finds the shortest path to the oxygen system with a breadth-first search
+// over cloned droids, without mapping out the maze first.
+func SolveASearch(r io.Reader) any {
+	return SearchOxygen(intcode.NewSyncInterpreter(r)).Depth
+}
+
+// SolveBSearch finds the time to fill the maze with oxygen by searching
+// for the farthest point reachable from the oxygen system.
+func SolveBSearch(r io.Reader) any {
+	oxygen := SearchOxygen(intcode.NewSyncInterpreter(r))
+	s := NewSearch()
+	s.Goal = nil
+	return s.Run(oxygen.M, oxygen.State).MaxDepth
+}
+
+func SolveA(r io.Reader) any { return SolveATraced(r, nil) }
+
+func SolveATraced(r io.Reader, t intcode.Tracer) any {
+	m, oxygen := GetMapTraced(r, t)
 	path := ShortestPath(m, Point{0, 0}, oxygen)
 	return len(path) - 1
 }
 
-func SolveB(r io.Reader) any {
-	m, _ := GetMap(r)
-	tilesToFill := maps2.CountValues(m, MapTileCorridor)
-	rounds := 0
-	for tilesToFill > 0 {
-		m2 := maps.Clone(m)
-		for pt, tile := range m {
-			if tile == MapTileOxygen {
-				// Iterate over of the neighbors
-				for _, d := range []Decision{DecisionNorth, DecisionSouth, DecisionWest, DecisionEast} {
-					// Check if the neighbor is accessible
-					toPt := pt.AfterDecision(d)
-					toState := m2[toPt]
-					if toState == MapTileCorridor {
-						m2[toPt] = MapTileOxygen
-						tilesToFill--
-					}
-				}
-			}
-		}
-
-		m = m2
-		rounds++
-	}
+func SolveB(r io.Reader) any { return SolveBTraced(r, nil) }
 
+func SolveBTraced(r io.Reader, t intcode.Tracer) any {
+	m, oxygen := GetMapTraced(r, t)
+	_, rounds := Farthest(DistanceField(m, oxygen))
 	return rounds
 }
@@ -0,0 +1,168 @@
+package day17
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFrame = errors.New("invalid camera frame")
+
+// Frame is a single image of the camera
+type Frame struct {
+	Grid []string
+
+	Robot        Robot // Facing is unknown if the robot is tumbling
+	RobotVisible bool
+	Tumbling     bool // The robot has fallen off the scaffolding, shown as 'X'
+}
+
+// isFrameLine returns true if a line contains only characters drawn by the camera
+func isFrameLine(line string) bool {
+	return line != "" && strings.Trim(line, ".#^v<>X") == ""
+}
+
+// ParseFrame parses the lines of a camera frame
+func ParseFrame(lines []string) (f Frame, err error) {
+	for y, line := range lines {
+		if line == "" {
+			continue
+		} else if !isFrameLine(line) {
+			return f, fmt.Errorf("%w: unexpected line %q", ErrInvalidFrame, line)
+		}
+
+		for x := 0; x < len(line); x++ {
+			facing, isRobot := robotFacing(line[x])
+			tumbling := line[x] == 'X'
+			if !isRobot && !tumbling {
+				continue
+			} else if f.RobotVisible {
+				return f, fmt.Errorf("%w: more than one robot", ErrInvalidFrame)
+			}
+
+			f.Robot = Robot{Point{x, y}, facing}
+			f.RobotVisible, f.Tumbling = true, tumbling
+		}
+	}
+
+	f.Grid = lines
+	for len(f.Grid) > 0 && f.Grid[len(f.Grid)-1] == "" {
+		f.Grid = f.Grid[:len(f.Grid)-1]
+	}
+	return f, nil
+}
+
+// Tile returns the character at the provided point, '.' if it's outside of the frame
+func (f Frame) Tile(p Point) byte {
+	if p.Y < 0 || p.Y >= len(f.Grid) || p.X < 0 || p.X >= len(f.Grid[p.Y]) {
+		return '.'
+	}
+	return f.Grid[p.Y][p.X]
+}
+
+// Scaffolding returns all visible scaffolding, including the tile below the robot
+// (unless the robot is tumbling through space)
+func (f Frame) Scaffolding() Scaffolding {
+	s := make(Scaffolding)
+	for y, line := range f.Grid {
+		for x := 0; x < len(line); x++ {
+			if _, isRobot := robotFacing(line[x]); isRobot || line[x] == '#' {
+				s.Add(Point{x, y})
+			}
+		}
+	}
+	return s
+}
+
+func (f Frame) String() string { return strings.Join(f.Grid, "\n") + "\n" }
+
+// FrameDecoder splits the ASCII output of the robot into frames. With the continuous video feed
+// enabled, the robot outputs a frame after every move; frames are separated by blank lines.
+type FrameDecoder struct {
+	Text []string // Lines of output which weren't part of any frame, like prompts
+	Dust int      // The only non-ASCII output, amount of collected dust
+
+	line  []byte
+	block []string
+}
+
+// Write handles a single output value. If it has completed a frame, the frame is returned.
+func (d *FrameDecoder) Write(c int) (f Frame, ok bool) {
+	if c >= 0x7F {
+		d.Dust = c
+		return d.Flush()
+	} else if c != '\n' {
+		d.line = append(d.line, byte(c))
+		return
+	}
+
+	line := string(d.line)
+	d.line = d.line[:0]
+
+	switch {
+	case line == "":
+		return d.Flush()
+	case isFrameLine(line):
+		d.block = append(d.block, line)
+	default:
+		d.Text = append(d.Text, line)
+	}
+	return
+}
+
+// Flush returns the last, unfinished frame (if there is one)
+func (d *FrameDecoder) Flush() (f Frame, ok bool) {
+	if len(d.block) == 0 {
+		return
+	}
+
+	f, err := ParseFrame(d.block)
+	d.block = nil
+	return f, err == nil
+}
+
+// Animation draws frames on an ANSI terminal, one over another
+type Animation struct {
+	W          io.Writer
+	FrameDelay time.Duration
+	Frames     int // Amount of drawn frames
+}
+
+// Draw shows a frame, and waits FrameDelay
+func (a *Animation) Draw(f Frame) {
+	b := &strings.Builder{}
+	if a.Frames == 0 {
+		b.WriteString("\x1b[2J")
+	}
+	b.WriteString("\x1b[H")
+	a.Frames++
+
+	state := "not visible"
+	if f.Tumbling {
+		state = fmt.Sprintf("tumbling at %d,%d", f.Robot.Pos.X, f.Robot.Pos.Y)
+	} else if f.RobotVisible {
+		state = fmt.Sprintf("at %d,%d", f.Robot.Pos.X, f.Robot.Pos.Y)
+	}
+	fmt.Fprintf(b, "Frame %d, robot %s\x1b[K\n", a.Frames, state)
+
+	for _, line := range f.Grid {
+		for x := 0; x < len(line); x++ {
+			switch c := line[x]; c {
+			case '#':
+				b.WriteString("\x1b[90m#\x1b[0m")
+			case '.':
+				b.WriteByte(' ')
+			case 'X':
+				b.WriteString("\x1b[1;31mX\x1b[0m")
+			default:
+				fmt.Fprintf(b, "\x1b[1;32m%c\x1b[0m", c)
+			}
+		}
+		b.WriteString("\x1b[K\n")
+	}
+
+	io.WriteString(a.W, b.String())
+	time.Sleep(a.FrameDelay)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/MKuranowski/AdventOfCode2019/intcode"
@@ -37,7 +38,9 @@ func ReceiveScreenOnce(s Scaffolding, ch <-chan int, wg *sync.WaitGroup) {
 	}
 }
 
-func SolveA(r io.Reader) any {
+func SolveA(r io.Reader) any { return SolveATraced(r, nil) }
+
+func SolveATraced(r io.Reader, t intcode.Tracer) any {
 	// Run the program and map out the scaffolding
 	scaffolding := make(Scaffolding)
 	i := intcode.NewInterpreterNewIO(r)
@@ -45,7 +48,7 @@ func SolveA(r io.Reader) any {
 	wg.Add(1)
 	close(i.Input)
 	go ReceiveScreenOnce(scaffolding, i.Output, wg)
-	i.ExecAll()
+	i.ExecAllTraced(t)
 	wg.Wait()
 
 	// Calculate the alignment
@@ -75,21 +78,6 @@ func SolveA(r io.Reader) any {
 	return alignment
 }
 
-// Figured out this by hand, lol
-// A C A B A B C B B C
-// A: L4 L4 L10 R4
-// B: R4 L10 R10
-// C: R4 L4 L4 R8 R10
-const solution = "A,C,A,B,A,B,C,B,B,C\nL,4,L,4,L,10,R,4\nR,4,L,10,R,10\nR,4,L,4,L,4,R,8,R,10\nn\n"
-
-func SolutionSender(ch chan<- int, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for _, b := range solution {
-		ch <- int(b)
-	}
-	close(ch)
-}
-
 type Screen struct {
 	LastNonASCII int
 }
@@ -102,26 +90,71 @@ func (s *Screen) Run(ch <-chan int, wg *sync.WaitGroup) {
 	}()
 
 	for c := range ch {
-		if c >= 0x7F {
-			s.LastNonASCII = c
-		} else {
-			fmt.Fprintf(os.Stderr, "%c", c)
-		}
+		s.Write(c)
 	}
 }
 
-func SolveB(r io.Reader) any {
-	s := Screen{}
-	i := intcode.NewInterpreterNewIO(r)
-	i.Memory[0] = 2
+// Write handles a single output value, printing ASCII characters to stderr
+func (s *Screen) Write(c int) {
+	if c >= 0x7F {
+		s.LastNonASCII = c
+	} else {
+		fmt.Fprintf(os.Stderr, "%c", c)
+	}
+}
 
-	wg := &sync.WaitGroup{}
-	wg.Add(2)
+// WakeUp wakes the vacuum robot up, so that it accepts movement routines
+var WakeUp = intcode.Patch{
+	Name:    "wake-up",
+	Entries: []intcode.PatchEntry{{Addr: 0, Value: 2, Check: true, Original: 1}},
+}
 
-	go s.Run(i.Output, wg)
-	go SolutionSender(i.Input, wg)
-	i.ExecAll()
+func SolveB(r io.Reader) any { return SolveBTraced(r, nil) }
 
-	wg.Wait()
+func SolveBTraced(r io.Reader, t intcode.Tracer) any {
+	s := Screen{}
+	RunRobot(r, t, false, s.Write)
 	return s.LastNonASCII
 }
+
+// RunRobot wakes the robot up, and feeds it movement routines derived from its camera image.
+// Every output value (including the initial camera image) is passed to out.
+// t is optional, and receives every executed instruction.
+func RunRobot(r io.Reader, t intcode.Tracer, video bool, out func(c int)) {
+	i := intcode.NewSyncInterpreter(r)
+	if err := i.ApplyPatch(WakeUp); err != nil {
+		panic(err)
+	}
+
+	// Run until the robot asks for the main routine, reading the camera image
+	i.ExecAllTraced(t)
+	image := &strings.Builder{}
+	for i.Output.Len() > 0 {
+		c := i.Output.PopFront()
+		image.WriteByte(byte(c))
+		out(c)
+	}
+
+	// Figure out the movement routines
+	scaffolding, robot, err := ParseCamera(image.String())
+	if err != nil {
+		panic(err)
+	}
+	routines, err := FindRoutines(TracePath(scaffolding, robot))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range routines.Input(video) {
+		i.Input.PushBack(int(c))
+	}
+
+	// Let the robot collect the dust
+	if state := i.ExecAllTraced(t); state != intcode.SyncExecutionStateHalted {
+		panic("vacuum robot didn't accept the movement routines")
+	}
+
+	for i.Output.Len() > 0 {
+		out(i.Output.PopFront())
+	}
+}
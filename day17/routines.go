@@ -0,0 +1,207 @@
+package day17
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoRobot    = errors.New("robot not visible on the camera image")
+	ErrNoRoutines = errors.New("path can't be split into movement functions")
+)
+
+// MaxRoutineLength is the maximum amount of characters of a movement routine, excluding the newline
+const MaxRoutineLength = 20
+
+// Robot is the position of the vacuum robot, and the direction it's facing
+type Robot struct {
+	Pos    Point
+	Facing Point // Unit vector, Y grows downwards
+}
+
+func (r Robot) left() Point  { return Point{r.Facing.Y, -r.Facing.X} }
+func (r Robot) right() Point { return Point{-r.Facing.Y, r.Facing.X} }
+
+func (p Point) add(o Point) Point { return Point{p.X + o.X, p.Y + o.Y} }
+
+// robotFacing returns the direction of a robot drawn with the provided character
+func robotFacing(c byte) (facing Point, ok bool) {
+	switch c {
+	case '^':
+		return Point{0, -1}, true
+	case 'v':
+		return Point{0, 1}, true
+	case '<':
+		return Point{-1, 0}, true
+	case '>':
+		return Point{1, 0}, true
+	default:
+		return Point{}, false
+	}
+}
+
+// ParseCamera reads the scaffolding and the robot from the first frame of the camera
+func ParseCamera(image string) (s Scaffolding, r Robot, err error) {
+	image, _, _ = strings.Cut(image, "\n\n")
+	f, err := ParseFrame(strings.Split(image, "\n"))
+	if err != nil {
+		return nil, r, err
+	} else if !f.RobotVisible || f.Tumbling {
+		return f.Scaffolding(), r, ErrNoRobot
+	}
+	return f.Scaffolding(), f.Robot, nil
+}
+
+// Move is a single instruction of a movement function - an optional turn ('L' or 'R'),
+// followed by an optional amount of steps forward
+type Move struct {
+	Turn  byte
+	Steps int
+}
+
+func (m Move) String() string {
+	switch {
+	case m.Turn == 0:
+		return strconv.Itoa(m.Steps)
+	case m.Steps == 0:
+		return string(m.Turn)
+	default:
+		return string(m.Turn) + "," + strconv.Itoa(m.Steps)
+	}
+}
+
+type Path []Move
+
+func (p Path) String() string {
+	moves := make([]string, len(p))
+	for idx, m := range p {
+		moves[idx] = m.String()
+	}
+	return strings.Join(moves, ",")
+}
+
+// TracePath follows the scaffolding from the robot's position until its end,
+// going straight through intersections.
+func TracePath(s Scaffolding, r Robot) (path Path) {
+	// The robot might need to turn around first
+	if !s.Has(r.Pos.add(r.Facing)) && !s.Has(r.Pos.add(r.left())) && !s.Has(r.Pos.add(r.right())) {
+		r.Facing = r.right()
+		path = append(path, Move{Turn: 'R'})
+	}
+
+	for {
+		m := Move{}
+		switch {
+		case s.Has(r.Pos.add(r.Facing)):
+			// Only possible on the very first move
+		case s.Has(r.Pos.add(r.left())):
+			m.Turn, r.Facing = 'L', r.left()
+		case s.Has(r.Pos.add(r.right())):
+			m.Turn, r.Facing = 'R', r.right()
+		default:
+			return
+		}
+
+		for s.Has(r.Pos.add(r.Facing)) {
+			r.Pos = r.Pos.add(r.Facing)
+			m.Steps++
+		}
+		path = append(path, m)
+	}
+}
+
+// Routines are the main movement routine and the movement functions of the robot
+type Routines struct {
+	Main      []int // Indices of called functions
+	Functions [3]Path
+}
+
+// Input returns the text expected by the robot, with the video feed enabled or disabled
+func (r Routines) Input(video bool) string {
+	b := &strings.Builder{}
+	for idx, f := range r.Main {
+		if idx > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(byte('A' + f))
+	}
+	b.WriteByte('\n')
+
+	for _, f := range r.Functions {
+		b.WriteString(f.String())
+		b.WriteByte('\n')
+	}
+
+	if video {
+		b.WriteString("y\n")
+	} else {
+		b.WriteString("n\n")
+	}
+	return b.String()
+}
+
+func hasPrefix(path, prefix Path) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for idx := range prefix {
+		if path[idx] != prefix[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// FindRoutines splits a path into a main routine calling at most 3 movement functions,
+// so that no routine is longer than MaxRoutineLength characters.
+func FindRoutines(path Path) (Routines, error) {
+	r := Routines{}
+	if !findRoutines(path, &r, 0) {
+		return r, ErrNoRoutines
+	}
+
+	// Unused functions still have to be provided
+	for idx := range r.Functions {
+		if r.Functions[idx] == nil {
+			r.Functions[idx] = r.Functions[0]
+		}
+	}
+	return r, nil
+}
+
+// findRoutines does a depth-first search, extending the main routine with a call to
+// one of the defined functions, or to a new function taking the following moves.
+func findRoutines(path Path, r *Routines, defined int) bool {
+	if len(path) == 0 {
+		return true
+	} else if 2*len(r.Main)+1 > MaxRoutineLength {
+		// No space left for another call
+		return false
+	}
+
+	for f := 0; f < defined; f++ {
+		if hasPrefix(path, r.Functions[f]) {
+			r.Main = append(r.Main, f)
+			if findRoutines(path[len(r.Functions[f]):], r, defined) {
+				return true
+			}
+			r.Main = r.Main[:len(r.Main)-1]
+		}
+	}
+
+	if defined == len(r.Functions) {
+		return false
+	}
+
+	for n := 1; n <= len(path) && len(path[:n].String()) <= MaxRoutineLength; n++ {
+		r.Functions[defined] = path[:n]
+		r.Main = append(r.Main, defined)
+		if findRoutines(path[n:], r, defined+1) {
+			return true
+		}
+		r.Main = r.Main[:len(r.Main)-1]
+	}
+	r.Functions[defined] = nil
+	return false
+}
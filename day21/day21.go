@@ -17,7 +17,15 @@ const SolutionA = "OR A T\nAND B T\nAND C T\nNOT T J\nAND D J\nWALK\n"
 // J = (~A or ~B or ~C) and D and (E or H)
 const SolutionB = "OR A T\nAND B T\nAND C T\nNOT T J\nAND D J\nOR E T\nOR H T\nAND T J\nRUN\n"
 
-func Solve(r io.Reader, solution string) int {
+// FormulaA and FormulaB are the above solutions, before compilation (see Compile)
+const (
+	FormulaA = "(!A | !B | !C) & D"
+	FormulaB = "(!A | !B | !C) & D & (E | H)"
+)
+
+func Solve(r io.Reader, solution string) int { return SolveTraced(r, solution, nil) }
+
+func SolveTraced(r io.Reader, solution string, t intcode.Tracer) int {
 	s := day17.Screen{}
 	i := intcode.NewInterpreterNewIO(r)
 
@@ -26,7 +34,7 @@ func Solve(r io.Reader, solution string) int {
 
 	go s.Run(i.Output, wg)
 	go input.AsciiStaticSender(i.Input, wg, solution)
-	i.ExecAll()
+	i.ExecAllTraced(t)
 
 	wg.Wait()
 	return s.LastNonASCII
@@ -34,3 +42,31 @@ func Solve(r io.Reader, solution string) int {
 
 func SolveA(r io.Reader) any { return Solve(r, SolutionA) }
 func SolveB(r io.Reader) any { return Solve(r, SolutionB) }
+
+// SolveCompiled compiles a formula into springscript, and runs it
+func SolveCompiled(r io.Reader, formula string, run bool) int {
+	p, err := CompileString(formula, run)
+	if err != nil {
+		panic(err)
+	}
+	return Solve(r, p.String())
+}
+
+func SolveACompiled(r io.Reader) any { return SolveCompiled(r, FormulaA, false) }
+func SolveBCompiled(r io.Reader) any { return SolveCompiled(r, FormulaB, true) }
+
+// SolveSearch learns a springscript program from the hulls the springdroid falls into (see Search.Learn)
+func SolveSearch(r io.Reader, run bool) int {
+	s := &Search{Run: run}
+	_, _, damage, err := s.Learn(Droid{intcode.NewSyncInterpreter(r)}, MaxRounds)
+	if err != nil {
+		panic(err)
+	}
+	return damage
+}
+
+func SolveASearch(r io.Reader) any { return SolveSearch(r, false) }
+func SolveBSearch(r io.Reader) any { return SolveSearch(r, true) }
+
+func SolveATraced(r io.Reader, t intcode.Tracer) any { return SolveTraced(r, SolutionA, t) }
+func SolveBTraced(r io.Reader, t intcode.Tracer) any { return SolveTraced(r, SolutionB, t) }
@@ -0,0 +1,237 @@
+package day21
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidFormula = errors.New("invalid formula")
+
+// Formula is a boolean expression over the sensors of the springdroid
+type Formula interface {
+	// Eval evaluates the formula, with bit 0 of sensors being the value of A, bit 1 of B, and so on.
+	Eval(sensors uint16) bool
+	String() string
+}
+
+// Sensor reads a register of the springdroid, 'A' to 'I'
+type Sensor byte
+
+func (s Sensor) Eval(sensors uint16) bool { return sensors&(1<<(s-'A')) != 0 }
+func (s Sensor) String() string           { return string(s) }
+
+type Not struct{ F Formula }
+
+func (n Not) Eval(sensors uint16) bool { return !n.F.Eval(sensors) }
+func (n Not) String() string {
+	if _, isSensor := n.F.(Sensor); isSensor {
+		return "!" + n.F.String()
+	}
+	return "!(" + n.F.String() + ")"
+}
+
+type And []Formula
+
+func (a And) Eval(sensors uint16) bool {
+	for _, f := range a {
+		if !f.Eval(sensors) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a And) String() string { return joinFormulas(a, " & ") }
+
+type Or []Formula
+
+func (o Or) Eval(sensors uint16) bool {
+	for _, f := range o {
+		if f.Eval(sensors) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Or) String() string { return joinFormulas(o, " | ") }
+
+func joinFormulas(fs []Formula, sep string) string {
+	parts := make([]string, len(fs))
+	for idx, f := range fs {
+		parts[idx] = f.String()
+		if len(fs) > 1 {
+			switch f.(type) {
+			case And, Or:
+				parts[idx] = "(" + parts[idx] + ")"
+			}
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// UsedSensors returns a bitmask of sensors used by the formula, bit 0 being A
+func UsedSensors(f Formula) (used uint16) {
+	switch f := f.(type) {
+	case Sensor:
+		return 1 << (f - 'A')
+	case Not:
+		return UsedSensors(f.F)
+	case And:
+		for _, g := range f {
+			used |= UsedSensors(g)
+		}
+	case Or:
+		for _, g := range f {
+			used |= UsedSensors(g)
+		}
+	}
+	return
+}
+
+// Negate returns a formula equal to !f, with the negation pushed down to the sensors
+func Negate(f Formula) Formula {
+	switch f := f.(type) {
+	case Sensor:
+		return Not{f}
+	case Not:
+		return f.F
+	case And:
+		o := make(Or, len(f))
+		for idx, g := range f {
+			o[idx] = Negate(g)
+		}
+		return o
+	case Or:
+		a := make(And, len(f))
+		for idx, g := range f {
+			a[idx] = Negate(g)
+		}
+		return a
+	default:
+		panic(fmt.Errorf("invalid formula type: %T", f))
+	}
+}
+
+// ParseFormula parses a boolean formula over the sensors A to I, like "(!A | !B | !C) & D & (E | H)".
+// Negation is written as "!" or "~", conjunction as "&" and alternative as "|"; "&" binds stronger than "|".
+func ParseFormula(s string) (f Formula, err error) {
+	p := &formulaParser{s: s}
+	defer func() {
+		if r := recover(); r != nil {
+			if pErr, ok := r.(formulaError); ok {
+				err = fmt.Errorf("%w: %s at offset %d", ErrInvalidFormula, pErr.msg, pErr.offset)
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	f = p.or()
+	if p.peek() != 0 {
+		p.fail("unexpected %q", p.peek())
+	}
+	return f, nil
+}
+
+type formulaError struct {
+	msg    string
+	offset int
+}
+
+type formulaParser struct {
+	s   string
+	pos int
+}
+
+func (p *formulaParser) fail(format string, args ...any) {
+	panic(formulaError{fmt.Sprintf(format, args...), p.pos})
+}
+
+// peek returns the next non-space character, or 0 at the end of input
+func (p *formulaParser) peek() byte {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *formulaParser) or() Formula {
+	o := Or{p.and()}
+	for p.peek() == '|' {
+		p.pos++
+		o = append(o, p.and())
+	}
+	return flatten(o)
+}
+
+func (p *formulaParser) and() Formula {
+	a := And{p.unary()}
+	for p.peek() == '&' {
+		p.pos++
+		a = append(a, p.unary())
+	}
+	return flatten(a)
+}
+
+func (p *formulaParser) unary() Formula {
+	switch c := p.peek(); {
+	case c == '!' || c == '~':
+		p.pos++
+		return Not{p.unary()}
+	case c == '(':
+		p.pos++
+		f := p.or()
+		if p.peek() != ')' {
+			p.fail("expected ')'")
+		}
+		p.pos++
+		return f
+	case c >= 'A' && c <= 'I':
+		p.pos++
+		return Sensor(c)
+	case c == 0:
+		p.fail("unexpected end of formula")
+	default:
+		p.fail("unexpected %q", c)
+	}
+	return nil
+}
+
+// flatten merges nested conjunctions (or alternatives), and unwraps single-element ones
+func flatten(f Formula) Formula {
+	switch f := f.(type) {
+	case And:
+		flat := And{}
+		for _, g := range f {
+			if nested, ok := g.(And); ok {
+				flat = append(flat, nested...)
+			} else {
+				flat = append(flat, g)
+			}
+		}
+		if len(flat) == 1 {
+			return flat[0]
+		}
+		return flat
+	case Or:
+		flat := Or{}
+		for _, g := range f {
+			if nested, ok := g.(Or); ok {
+				flat = append(flat, nested...)
+			} else {
+				flat = append(flat, g)
+			}
+		}
+		if len(flat) == 1 {
+			return flat[0]
+		}
+		return flat
+	default:
+		return f
+	}
+}
@@ -0,0 +1,309 @@
+package day21
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+var ErrNoProgram = errors.New("no springscript program crosses all hulls")
+
+// Search learns a springscript program crossing all of the known hulls
+type Search struct {
+	Run   bool
+	Hulls []Hull
+}
+
+const (
+	MaxPlans  = 1000 // Maximum amount of jump plans generalized by Search.Candidate
+	MaxRounds = 100  // Default amount of candidates tested by Search.Learn
+)
+
+// Candidate returns a program crossing all of the known hulls offline. Decisions whether to jump
+// are planned for every reading of the sensors met on the hulls, and then generalized into a formula;
+// out of several plans, the shortest formula which can be compiled is used.
+func (s *Search) Candidate() (p Program, f Formula, err error) {
+	var formulas []Formula
+	seen := make(map[string]bool)
+	s.plan(make(map[uint16]bool), 0, 0, func(decisions map[uint16]bool) bool {
+		f := generalize(decisions, s.Run)
+		if !seen[f.String()] {
+			seen[f.String()] = true
+			formulas = append(formulas, f)
+		}
+		return len(seen) >= MaxPlans
+	})
+
+	if len(formulas) == 0 {
+		return Program{Run: s.Run}, nil, ErrNoProgram
+	}
+
+	sort.SliceStable(formulas, func(i, j int) bool { return literals(formulas[i]) < literals(formulas[j]) })
+	for _, f = range formulas {
+		p, err = Compile(f, s.Run)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// Sanity check
+	for _, h := range s.Hulls {
+		if !p.Simulate(h).Survived {
+			panic(fmt.Errorf("springscript compiled from %s doesn't cross %s", f, h))
+		}
+	}
+	return p, f, nil
+}
+
+// Learn alternates between finding candidates offline and testing them on the droid,
+// adding the hulls which the droid fell into, until a candidate makes it across.
+// At most maxRounds candidates are tested.
+func (s *Search) Learn(d Droid, maxRounds int) (p Program, f Formula, damage int, err error) {
+	for round := 0; round < maxRounds; round++ {
+		p, f, err = s.Candidate()
+		if err != nil {
+			return
+		}
+
+		damage, failed, survived := d.Test(p)
+		if survived {
+			return p, f, damage, nil
+		}
+
+		for _, h := range s.Hulls {
+			if h == failed {
+				panic(fmt.Errorf("droid fell into %s, which the simulator crosses", h))
+			}
+		}
+		s.Hulls = append(s.Hulls, failed)
+	}
+	return p, f, 0, fmt.Errorf("%w: gave up after %d rounds", ErrNoProgram, maxRounds)
+}
+
+// plan does a depth-first search for jump decisions crossing hulls from the provided one,
+// with the springdroid standing on tile x. Every found plan is passed to visit, which returns true
+// to stop the search. Walking is preferred over jumping.
+func (s *Search) plan(decisions map[uint16]bool, hull, x int, visit func(map[uint16]bool) bool) (stop bool) {
+	for ; hull < len(s.Hulls); hull, x = hull+1, 0 {
+		h := s.Hulls[hull]
+		for x < len(h) {
+			if !h.Ground(x) {
+				return false
+			}
+
+			sensors := h.Sensors(x, s.Run)
+			jump, decided := decisions[sensors]
+			if !decided {
+				for _, jump := range []bool{false, true} {
+					decisions[sensors] = jump
+					if s.plan(decisions, hull, x+distance(jump), visit) {
+						return true
+					}
+				}
+				delete(decisions, sensors)
+				return false
+			}
+			x += distance(jump)
+		}
+	}
+	return visit(decisions)
+}
+
+func distance(jump bool) int {
+	if jump {
+		return JumpDistance
+	}
+	return 1
+}
+
+// generalize turns planned decisions into a formula, true for every reading where the springdroid
+// has to jump, and false where it has to walk. Every jump is described by the smallest conjunction of
+// its readings excluding all walks (see implicant).
+func generalize(decisions map[uint16]bool, run bool) Formula {
+	last := Sensor('D')
+	if run {
+		last = 'I'
+	}
+
+	var jumps, walks []uint16
+	for sensors, jump := range decisions {
+		if jump {
+			jumps = append(jumps, sensors)
+		} else {
+			walks = append(walks, sensors)
+		}
+	}
+	sortReadings(jumps)
+
+	terms := Or{}
+	for _, sensors := range jumps {
+		if Or(terms).Eval(sensors) {
+			continue
+		}
+
+		term, _ := implicant(sensors, walks, last)
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 0 {
+		// Never jump: J is false from the start, but the formula must read some sensor
+		return And{Sensor('A'), Not{Sensor('A')}}
+	}
+	return factor(flatten(terms))
+}
+
+// implicant returns the smallest conjunction of sensor readings (preferring closer sensors)
+// true for sensors, and false for all of the walks. Returns false if there's no such conjunction.
+func implicant(sensors uint16, walks []uint16, last Sensor) (Formula, bool) {
+	n := int(last-'A') + 1
+	best, found := 0, false
+	for subset := 1; subset < 1<<n; subset++ {
+		if found && !closer(subset, best) {
+			continue
+		}
+
+		term := And{}
+		for s := Sensor('A'); s <= last; s++ {
+			if subset&(1<<(s-'A')) != 0 {
+				term = append(term, reading(s, sensors))
+			}
+		}
+		if excludes(term, walks) {
+			best, found = subset, true
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	term := And{}
+	for s := Sensor('A'); s <= last; s++ {
+		if best&(1<<(s-'A')) != 0 {
+			term = append(term, reading(s, sensors))
+		}
+	}
+	return flatten(term), true
+}
+
+// closer returns true if subset a of sensors is smaller than b, or if it's as large
+// and its farthest sensor is closer
+func closer(a, b int) bool {
+	if bits.OnesCount(uint(a)) != bits.OnesCount(uint(b)) {
+		return bits.OnesCount(uint(a)) < bits.OnesCount(uint(b))
+	}
+	return a < b
+}
+
+// reading returns a formula true if the sensor has the same value as in sensors
+func reading(s Sensor, sensors uint16) Formula {
+	if s.Eval(sensors) {
+		return s
+	}
+	return Not{s}
+}
+
+func excludes(f Formula, readings []uint16) bool {
+	for _, sensors := range readings {
+		if f.Eval(sensors) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortReadings orders readings with more ground first, so that the springdroid jumps as early as possible
+func sortReadings(readings []uint16) {
+	ground := func(sensors uint16) int { return bits.OnesCount16(sensors) }
+
+	for i := 1; i < len(readings); i++ {
+		for j := i; j > 0; j-- {
+			a, b := readings[j-1], readings[j]
+			if ground(a) > ground(b) || (ground(a) == ground(b) && a > b) {
+				break
+			}
+			readings[j-1], readings[j] = b, a
+		}
+	}
+}
+
+// factor pulls literals shared by all terms of an alternative out, e.g. (!A & D) | (!B & D) becomes D & (!A | !B)
+func factor(f Formula) Formula {
+	terms, isOr := f.(Or)
+	if !isOr {
+		return f
+	}
+
+	var shared And
+	for _, candidate := range conjuncts(terms[0]) {
+		inAll := true
+		for _, term := range terms[1:] {
+			if !containsLiteral(conjuncts(term), candidate) {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			shared = append(shared, candidate)
+		}
+	}
+
+	if len(shared) == 0 {
+		return f
+	}
+
+	rest := Or{}
+	for _, term := range terms {
+		remaining := And{}
+		for _, l := range conjuncts(term) {
+			if !containsLiteral(shared, l) {
+				remaining = append(remaining, l)
+			}
+		}
+		if len(remaining) == 0 {
+			// A term is made only of shared literals, so it covers all other terms
+			return flatten(shared)
+		}
+		rest = append(rest, flatten(remaining))
+	}
+	return flatten(append(shared, factor(flatten(rest))))
+}
+
+// literals returns the amount of sensors read by a formula, counting repeated reads
+func literals(f Formula) (n int) {
+	switch f := f.(type) {
+	case Sensor:
+		return 1
+	case Not:
+		return literals(f.F)
+	case And:
+		for _, g := range f {
+			n += literals(g)
+		}
+	case Or:
+		for _, g := range f {
+			n += literals(g)
+		}
+	}
+	return
+}
+
+func conjuncts(f Formula) []Formula {
+	if a, isAnd := f.(And); isAnd {
+		return a
+	}
+	return []Formula{f}
+}
+
+func containsLiteral(literals []Formula, l Formula) bool {
+	for _, m := range literals {
+		if m == l {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package day21
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+var ErrInvalidHull = errors.New("invalid hull")
+
+// JumpDistance is the amount of tiles the springdroid moves forward with a single jump
+const JumpDistance = 4
+
+// Hull is a row of tiles seen from the side, like "#####.#..########", with '#' being ground
+// and '.' being holes. The springdroid starts on the first tile, and everything after the last tile is ground.
+type Hull string
+
+// ParseHull checks that a string only contains ground and holes
+func ParseHull(s string) (Hull, error) {
+	if s == "" || strings.Trim(s, "#.") != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidHull, s)
+	}
+	return Hull(s), nil
+}
+
+// ReadHulls reads hulls, one per line; blank lines are ignored
+func ReadHulls(r io.Reader) (hulls []Hull, err error) {
+	for _, line := range input.ReadLines(r) {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+
+		h, err := ParseHull(line)
+		if err != nil {
+			return nil, err
+		}
+		hulls = append(hulls, h)
+	}
+	return
+}
+
+// WriteHulls writes hulls, one per line
+func WriteHulls(w io.Writer, hulls []Hull) error {
+	for _, h := range hulls {
+		if _, err := fmt.Fprintln(w, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ground returns true if there's no hole at the provided tile
+func (h Hull) Ground(x int) bool { return x < 0 || x >= len(h) || h[x] == '#' }
+
+// Sensors returns the readings of a springdroid standing at x,
+// with bit 0 being A (ground one tile away), bit 1 being B, and so on.
+// Without run, only sensors A to D are available.
+func (h Hull) Sensors(x int, run bool) (sensors uint16) {
+	last := 'D'
+	if run {
+		last = 'I'
+	}
+
+	for s := 'A'; s <= last; s++ {
+		if h.Ground(x + int(s-'A') + 1) {
+			sensors |= 1 << (s - 'A')
+		}
+	}
+	return
+}
+
+// Outcome describes a simulated attempt to cross a hull
+type Outcome struct {
+	Survived bool
+	Fell     int   // Tile where the springdroid fell into space, if it didn't survive
+	Jumps    []int // Tiles from which the springdroid jumped
+}
+
+// Simulate walks the springdroid over the hull, deciding on every tile whether to jump.
+func Simulate(h Hull, run bool, jump func(sensors uint16) bool) (o Outcome) {
+	for x := 0; x < len(h); {
+		if !h.Ground(x) {
+			o.Fell = x
+			return
+		}
+
+		if jump(h.Sensors(x, run)) {
+			o.Jumps = append(o.Jumps, x)
+			x += JumpDistance
+		} else {
+			x++
+		}
+	}
+
+	o.Survived = true
+	return
+}
+
+// Simulate walks the springdroid controlled by the program over the hull
+func (p Program) Simulate(h Hull) Outcome { return Simulate(h, p.Run, p.Jump) }
+
+// Draw returns the hull with the path of the springdroid beneath it, like:
+//
+//	#####.#..########
+//	    ^   ^
+//
+// where '^' marks jumps and 'X' marks the fall.
+func (o Outcome) Draw(h Hull) string {
+	marks := []byte(strings.Repeat(" ", len(h)+JumpDistance))
+	for _, x := range o.Jumps {
+		marks[x] = '^'
+	}
+	if !o.Survived {
+		marks[o.Fell] = 'X'
+	}
+	return string(h) + "\n" + strings.TrimRight(string(marks), " ")
+}
+
+// ParseReport extracts the hull the springdroid fell into from the output of the intcode program,
+// which shows the last moments of the springdroid after "Didn't make it across:".
+func ParseReport(output string) (h Hull, ok bool) {
+	_, report, found := strings.Cut(output, "Didn't make it across:")
+	if !found {
+		return "", false
+	}
+
+	// The first frame starts with the springdroid standing on the first tile, so its hull
+	// is the first line with some ground (the air above the hull is all '.').
+	for _, line := range strings.Split(report, "\n") {
+		if h, err := ParseHull(line); err == nil && strings.Contains(line, "#") {
+			return h, true
+		}
+	}
+	return "", false
+}
+
+// Droid runs springscript programs on the real springdroid
+type Droid struct {
+	M *intcode.SyncInterpreter // Fresh intcode program, cloned for every run
+}
+
+// Test sends the program to the springdroid. If it made it across, the reported hull damage is returned;
+// otherwise the hull which the springdroid fell into is returned.
+func (d Droid) Test(p Program) (damage int, failed Hull, survived bool) {
+	m := d.M.Clone()
+	for _, c := range p.String() {
+		m.Input.PushBack(int(c))
+	}
+	if state := m.ExecAll(); state != intcode.SyncExecutionStateHalted {
+		panic(fmt.Errorf("springdroid didn't accept the program:\n%s", p))
+	}
+
+	output := &strings.Builder{}
+	for m.Output.Len() > 0 {
+		c := m.Output.PopFront()
+		if c > 0x7F {
+			return c, "", true
+		}
+		output.WriteByte(byte(c))
+	}
+
+	failed, ok := ParseReport(output.String())
+	if !ok {
+		panic(fmt.Errorf("unexpected springdroid output:\n%s", output))
+	}
+	return 0, failed, false
+}
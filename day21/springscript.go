@@ -0,0 +1,457 @@
+package day21
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrSensorUnavailable   = errors.New("sensors E to I are only available in RUN mode")
+	ErrTooManyInstructions = errors.New("springscript program is too long")
+	ErrInexpressible       = errors.New("formula can't be expressed in springscript")
+)
+
+// MaxInstructions is the maximum length of a springscript program, excluding WALK or RUN
+const MaxInstructions = 15
+
+// SearchBudget is the maximum amount of register states explored while looking for the shortest program
+const SearchBudget = 250_000
+
+// Register is a springscript register: a read-only sensor 'A' to 'I', or a writable 'T' or 'J'
+type Register byte
+
+const (
+	RegisterT Register = 'T'
+	RegisterJ Register = 'J'
+)
+
+func (r Register) other() Register {
+	if r == RegisterT {
+		return RegisterJ
+	}
+	return RegisterT
+}
+
+type Op uint8
+
+const (
+	OpAnd Op = iota
+	OpOr
+	OpNot
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpAnd:
+		return "AND"
+	case OpOr:
+		return "OR"
+	case OpNot:
+		return "NOT"
+	default:
+		return "???"
+	}
+}
+
+// Instruction is a single springscript instruction, "AND X Y" sets Y to X & Y,
+// "OR X Y" sets Y to X | Y and "NOT X Y" sets Y to !X.
+type Instruction struct {
+	Op   Op
+	X, Y Register
+}
+
+func (i Instruction) String() string { return fmt.Sprintf("%s %c %c", i.Op, i.X, i.Y) }
+
+// Program is a springscript program. The springdroid jumps if J is true after executing it.
+type Program struct {
+	Instructions []Instruction
+	Run          bool // Whether the program ends with RUN (with sensors up to I) instead of WALK
+
+	// Minimal is set by Compile if no shorter program computes the same formula
+	Minimal bool
+}
+
+// String returns the springscript source, ready to be sent to the springdroid
+func (p Program) String() string {
+	b := &strings.Builder{}
+	for _, i := range p.Instructions {
+		b.WriteString(i.String())
+		b.WriteByte('\n')
+	}
+	if p.Run {
+		b.WriteString("RUN\n")
+	} else {
+		b.WriteString("WALK\n")
+	}
+	return b.String()
+}
+
+// Jump executes the program, returning the value of J.
+// Bit 0 of sensors is the value of A, bit 1 of B, and so on.
+func (p Program) Jump(sensors uint16) bool {
+	var t, j bool
+	read := func(r Register) bool {
+		switch r {
+		case RegisterT:
+			return t
+		case RegisterJ:
+			return j
+		default:
+			return Sensor(r).Eval(sensors)
+		}
+	}
+
+	for _, i := range p.Instructions {
+		x, y := read(i.X), read(i.Y)
+		switch i.Op {
+		case OpAnd:
+			y = x && y
+		case OpOr:
+			y = x || y
+		case OpNot:
+			y = !x
+		}
+
+		if i.Y == RegisterT {
+			t = y
+		} else {
+			j = y
+		}
+	}
+	return j
+}
+
+// Compile turns a formula into the shortest springscript program setting J to its value
+// it can find. Sensors E to I may only be used if run is set.
+func Compile(f Formula, run bool) (p Program, err error) {
+	p.Run = run
+	used := UsedSensors(f)
+	if !run && used&^0b1111 != 0 {
+		return p, fmt.Errorf("%w: %s", ErrSensorUnavailable, f)
+	}
+
+	// Translate the formula directly
+	code, _, ok := generate(f, RegisterJ, true, registerState{true, true}, true)
+
+	// Look for something shorter
+	table := newTruthTables(used)
+	limit := MaxInstructions
+	if ok {
+		limit = len(code) - 1
+	}
+	shorter, found, exhaustive := table.search(table.of(f), limit, SearchBudget)
+
+	switch {
+	case found:
+		p.Instructions, p.Minimal = shorter, true
+	case ok:
+		p.Instructions, p.Minimal = code, exhaustive
+	case exhaustive:
+		return p, fmt.Errorf("%w: %s needs more than %d instructions", ErrTooManyInstructions, f, MaxInstructions)
+	default:
+		return p, fmt.Errorf("%w: %s", ErrInexpressible, f)
+	}
+
+	// Double-check the result
+	for row := 0; row < table.rows; row++ {
+		sensors := table.sensors(row)
+		if p.Jump(sensors) != f.Eval(sensors) {
+			panic(fmt.Errorf("springscript compiled from %s is invalid:\n%s", f, p))
+		}
+	}
+
+	if len(p.Instructions) > MaxInstructions {
+		return p, fmt.Errorf("%w: %s compiles to %d instructions, more than the limit of %d",
+			ErrTooManyInstructions, f, len(p.Instructions), MaxInstructions)
+	}
+	return p, nil
+}
+
+// CompileString parses and compiles a formula
+func CompileString(formula string, run bool) (Program, error) {
+	f, err := ParseFormula(formula)
+	if err != nil {
+		return Program{}, err
+	}
+	return Compile(f, run)
+}
+
+// registerState remembers which writable registers are known to be false
+type registerState struct{ tFalse, jFalse bool }
+
+func (s registerState) isFalse(r Register) bool {
+	if r == RegisterT {
+		return s.tFalse
+	}
+	return s.jFalse
+}
+
+func (s registerState) dirty(r Register) registerState {
+	if r == RegisterT {
+		s.tFalse = false
+	} else {
+		s.jFalse = false
+	}
+	return s
+}
+
+// generate returns the shortest code it can find, computing f into dst. If scratch is set,
+// the other writable register may be overwritten. If negate is set, computing !f and negating
+// the result is also considered, as well as regrouping negated sensors (see regroup) - both
+// introduce negations, which would be undone by the alternatives considered for negations.
+// Returns false if f can't be computed with the available registers.
+func generate(f Formula, dst Register, scratch bool, st registerState, negate bool) (best []Instruction, bestSt registerState, ok bool) {
+	try := func(code []Instruction, s registerState, valid bool) {
+		if valid && (!ok || len(code) < len(best)) {
+			best, bestSt, ok = code, s, true
+		}
+	}
+
+	switch f := f.(type) {
+	case Sensor:
+		if st.isFalse(dst) {
+			try([]Instruction{{OpOr, Register(f), dst}}, st.dirty(dst), true)
+		} else {
+			try([]Instruction{{OpNot, Register(f), dst}, {OpNot, dst, dst}}, st.dirty(dst), true)
+		}
+
+	case Not:
+		if s, isSensor := f.F.(Sensor); isSensor {
+			try([]Instruction{{OpNot, Register(s), dst}}, st.dirty(dst), true)
+			break
+		}
+		try(generate(Negate(f.F), dst, scratch, st, false))
+
+		code, s, valid := generate(f.F, dst, scratch, st, false)
+		try(append(code, Instruction{OpNot, dst, dst}), s, valid)
+
+	case And:
+		try(generateJunction(OpAnd, f, dst, scratch, st))
+		if regrouped, ok := regroup(OpAnd, f); ok && negate {
+			try(generateJunction(OpAnd, regrouped, dst, scratch, st))
+		}
+
+	case Or:
+		try(generateJunction(OpOr, f, dst, scratch, st))
+		if regrouped, ok := regroup(OpOr, f); ok && negate {
+			try(generateJunction(OpOr, regrouped, dst, scratch, st))
+		}
+	}
+
+	if negate {
+		switch f.(type) {
+		case And, Or:
+			code, s, valid := generate(Negate(f), dst, scratch, st, false)
+			try(append(code, Instruction{OpNot, dst, dst}), s, valid)
+		}
+	}
+
+	return
+}
+
+// generateJunction computes a conjunction (or alternative) into dst. Every operand is tried
+// as the first one, loaded directly into dst; other operands are combined with op.
+func generateJunction(op Op, operands []Formula, dst Register, scratch bool, st registerState) (best []Instruction, bestSt registerState, ok bool) {
+	s := dst.other()
+
+	for first := range operands {
+		code, cur, valid := generate(operands[first], dst, scratch, st, true)
+
+		for idx := 0; valid && idx < len(operands); idx++ {
+			if idx == first {
+				continue
+			}
+
+			if sensor, isSensor := operands[idx].(Sensor); isSensor {
+				code = append(code, Instruction{op, Register(sensor), dst})
+				continue
+			} else if !scratch {
+				valid = false
+				break
+			}
+
+			var operand []Instruction
+			operand, cur, valid = generate(operands[idx], s, false, cur, true)
+			code = append(code, operand...)
+			code = append(code, Instruction{op, s, dst})
+		}
+
+		if valid && (!ok || len(code) < len(best)) {
+			best, bestSt, ok = code, cur.dirty(dst), true
+		}
+	}
+	return
+}
+
+// regroup collects negated sensors of a conjunction (or alternative) into a single negated operand,
+// e.g. !A & B & !C becomes !(A | C) & B, which needs no scratch register.
+func regroup(op Op, operands []Formula) (regrouped []Formula, ok bool) {
+	var negated []Formula
+	for _, f := range operands {
+		if n, isNot := f.(Not); isNot {
+			if s, isSensor := n.F.(Sensor); isSensor {
+				negated = append(negated, s)
+				continue
+			}
+		}
+		regrouped = append(regrouped, f)
+	}
+
+	if len(negated) < 2 {
+		return nil, false
+	} else if op == OpAnd {
+		return append(regrouped, Not{Or(negated)}), true
+	}
+	return append(regrouped, Not{And(negated)}), true
+}
+
+// truthTables describe formulas by their values for every combination of the used sensors
+type truthTables struct {
+	inputs []Register // Used sensors
+	rows   int
+	mask   truthTable
+}
+
+// truthTable has bit n set if a formula is true in row n
+type truthTable [8]uint64
+
+func newTruthTables(used uint16) *truthTables {
+	t := &truthTables{}
+	for s := Sensor('A'); s <= 'I'; s++ {
+		if used&(1<<(s-'A')) != 0 {
+			t.inputs = append(t.inputs, Register(s))
+		}
+	}
+
+	t.rows = 1 << len(t.inputs)
+	for row := 0; row < t.rows; row++ {
+		t.mask[row/64] |= 1 << (row % 64)
+	}
+	return t
+}
+
+// sensors returns the value of sensors in the provided row
+func (t *truthTables) sensors(row int) (sensors uint16) {
+	for idx, r := range t.inputs {
+		if row&(1<<idx) != 0 {
+			sensors |= 1 << (r - 'A')
+		}
+	}
+	return
+}
+
+func (t *truthTables) of(f Formula) (table truthTable) {
+	for row := 0; row < t.rows; row++ {
+		if f.Eval(t.sensors(row)) {
+			table[row/64] |= 1 << (row % 64)
+		}
+	}
+	return
+}
+
+type searchState struct{ t, j truthTable }
+
+type searchNode struct {
+	state  searchState
+	parent int
+	i      Instruction
+}
+
+func (t *truthTables) apply(s searchState, i Instruction, sensors map[Register]truthTable) searchState {
+	read := func(r Register) truthTable {
+		switch r {
+		case RegisterT:
+			return s.t
+		case RegisterJ:
+			return s.j
+		default:
+			return sensors[r]
+		}
+	}
+
+	x, y := read(i.X), read(i.Y)
+	for idx := range y {
+		switch i.Op {
+		case OpAnd:
+			y[idx] &= x[idx]
+		case OpOr:
+			y[idx] |= x[idx]
+		case OpNot:
+			y[idx] = ^x[idx] & t.mask[idx]
+		}
+	}
+
+	if i.Y == RegisterT {
+		s.t = y
+	} else {
+		s.j = y
+	}
+	return s
+}
+
+// search does a breadth-first search over register states for the shortest program
+// leaving target in J, with at most maxLength instructions. exhaustive is set if every program
+// up to maxLength instructions was considered, that is if the search didn't run out of budget.
+func (t *truthTables) search(target truthTable, maxLength, budget int) (code []Instruction, found, exhaustive bool) {
+	sensors := make(map[Register]truthTable, len(t.inputs))
+	for idx, r := range t.inputs {
+		for row := 0; row < t.rows; row++ {
+			if row&(1<<idx) != 0 {
+				table := sensors[r]
+				table[row/64] |= 1 << (row % 64)
+				sensors[r] = table
+			}
+		}
+	}
+
+	var instructions []Instruction
+	for _, op := range []Op{OpAnd, OpOr, OpNot} {
+		for _, x := range append(append([]Register(nil), t.inputs...), RegisterT, RegisterJ) {
+			for _, y := range []Register{RegisterT, RegisterJ} {
+				if x != y || op == OpNot {
+					instructions = append(instructions, Instruction{op, x, y})
+				}
+			}
+		}
+	}
+
+	nodes := []searchNode{{parent: -1}}
+	visited := map[searchState]bool{{}: true}
+	level := []int{0}
+
+	for length := 0; ; length++ {
+		for _, n := range level {
+			if nodes[n].state.j == target {
+				for ; nodes[n].parent >= 0; n = nodes[n].parent {
+					code = append(code, nodes[n].i)
+				}
+				for l, r := 0, len(code)-1; l < r; l, r = l+1, r-1 {
+					code[l], code[r] = code[r], code[l]
+				}
+				return code, true, true
+			}
+		}
+
+		if length == maxLength {
+			return nil, false, true
+		}
+
+		var next []int
+		for _, n := range level {
+			for _, i := range instructions {
+				s := t.apply(nodes[n].state, i, sensors)
+				if visited[s] {
+					continue
+				} else if len(nodes) >= budget {
+					return nil, false, false
+				}
+
+				visited[s] = true
+				nodes = append(nodes, searchNode{s, n, i})
+				next = append(next, len(nodes)-1)
+			}
+		}
+		level = next
+	}
+}
@@ -0,0 +1,207 @@
+package day23
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CapturedPacket is a packet sent through the network, along with the scheduler round it was sent in
+type CapturedPacket struct {
+	Round int `json:"round"`
+	Src   int `json:"src"`
+	Dest  int `json:"dest"`
+	X     int `json:"x"`
+	Y     int `json:"y"`
+}
+
+type CaptureFormat uint8
+
+const (
+	CaptureJSONLines CaptureFormat = iota
+	CaptureCSV
+)
+
+var csvHeader = []string{"round", "src", "dest", "x", "y"}
+
+// Capture writes every packet sent through a network, as JSON Lines or CSV with a header.
+type Capture struct {
+	Format CaptureFormat
+
+	w       *bufio.Writer
+	csv     *csv.Writer
+	started bool
+}
+
+func NewCapture(w io.Writer, format CaptureFormat) *Capture {
+	c := &Capture{Format: format, w: bufio.NewWriter(w)}
+	if format == CaptureCSV {
+		c.csv = csv.NewWriter(c.w)
+	}
+	return c
+}
+
+func (c *Capture) Write(p CapturedPacket) error {
+	if c.Format == CaptureJSONLines {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		c.w.Write(b)
+		return c.w.WriteByte('\n')
+	}
+
+	if !c.started {
+		c.started = true
+		if err := c.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	return c.csv.Write([]string{
+		strconv.Itoa(p.Round),
+		strconv.Itoa(p.Src),
+		strconv.Itoa(p.Dest),
+		strconv.Itoa(p.X),
+		strconv.Itoa(p.Y),
+	})
+}
+
+// Flush writes any buffered packets
+func (c *Capture) Flush() error {
+	if c.csv != nil {
+		c.csv.Flush()
+		if err := c.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// ReadCapture reads packets written by a Capture, detecting the format automatically.
+func ReadCapture(r io.Reader) (packets []CapturedPacket, err error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if first[0] == '{' {
+		d := json.NewDecoder(br)
+		for {
+			var p CapturedPacket
+			if err := d.Decode(&p); errors.Is(err, io.EOF) {
+				return packets, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("capture packet %d: %w", len(packets)+1, err)
+			}
+			packets = append(packets, p)
+		}
+	}
+
+	records, err := csv.NewReader(br).ReadAll()
+	if err != nil {
+		return nil, err
+	} else if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("capture: expected a CSV header %q", strings.Join(csvHeader, ","))
+	}
+
+	for idx, record := range records[1:] {
+		values := [5]int{}
+		for i, field := range record {
+			if values[i], err = strconv.Atoi(field); err != nil {
+				return nil, fmt.Errorf("capture line %d: %w", idx+2, err)
+			}
+		}
+		packets = append(packets, CapturedPacket{values[0], values[1], values[2], values[3], values[4]})
+	}
+	return packets, nil
+}
+
+// NICStats describes the traffic of a single address
+type NICStats struct {
+	Address  int
+	Sent     int
+	Received int // Amount of packets addressed to the NIC
+	FirstY   int // Y value of the first received packet
+	LastY    int // Y value of the last received packet
+}
+
+// CaptureStats summarizes captured traffic
+type CaptureStats struct {
+	NICs       []NICStats       // Sorted by address, excluding the NAT
+	NAT        []CapturedPacket // All packets sent to or by the NAT
+	NATAddress int
+	Rounds     int // Round of the last packet
+}
+
+// Analyze computes statistics of captured packets
+func Analyze(packets []CapturedPacket, natAddress int) (s CaptureStats) {
+	s.NATAddress = natAddress
+	nics := make(map[int]*NICStats)
+	nic := func(addr int) *NICStats {
+		if _, ok := nics[addr]; !ok {
+			nics[addr] = &NICStats{Address: addr}
+		}
+		return nics[addr]
+	}
+
+	for _, p := range packets {
+		s.Rounds = p.Round
+		if p.Src == natAddress || p.Dest == natAddress {
+			s.NAT = append(s.NAT, p)
+		}
+
+		if p.Src != natAddress {
+			nic(p.Src).Sent++
+		}
+		if p.Dest != natAddress {
+			receiver := nic(p.Dest)
+			if receiver.Received == 0 {
+				receiver.FirstY = p.Y
+			}
+			receiver.Received++
+			receiver.LastY = p.Y
+		}
+	}
+
+	for _, stats := range nics {
+		s.NICs = append(s.NICs, *stats)
+	}
+	sort.Slice(s.NICs, func(i, j int) bool { return s.NICs[i].Address < s.NICs[j].Address })
+	return
+}
+
+func (s CaptureStats) WriteTo(w io.Writer) (n int64, err error) {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d packets to or from the NAT, last packet in round %d\n\n", len(s.NAT), s.Rounds)
+
+	fmt.Fprintln(b, "address   sent  received  first y  last y")
+	for _, nic := range s.NICs {
+		fmt.Fprintf(b, "%7d %6d %9d", nic.Address, nic.Sent, nic.Received)
+		if nic.Received > 0 {
+			fmt.Fprintf(b, " %8d %7d", nic.FirstY, nic.LastY)
+		}
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprintln(b, "\nNAT history:")
+	for _, p := range s.NAT {
+		if p.Dest == s.NATAddress {
+			fmt.Fprintf(b, "round %6d: %3d -> NAT  x=%d y=%d\n", p.Round, p.Src, p.X, p.Y)
+		} else {
+			fmt.Fprintf(b, "round %6d: NAT -> %3d  x=%d y=%d\n", p.Round, p.Dest, p.X, p.Y)
+		}
+	}
+
+	written, err := io.WriteString(w, b.String())
+	return int64(written), err
+}
@@ -0,0 +1,278 @@
+package day23
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+	"github.com/MKuranowski/AdventOfCode2019/util/deque"
+)
+
+// DefaultIdlePolls is the amount of empty input polls of every NIC after which
+// a ConcurrentNetwork without any activity is considered idle
+const DefaultIdlePolls = 2
+
+type concurrentNIC struct {
+	*intcode.Interpreter
+	queue deque.Deque[int] // Guarded by ConcurrentNetwork.mu
+	polls atomic.Uint64    // Amount of times -1 was supplied as input
+}
+
+// ConcurrentNetwork runs every NIC as an intcode.Interpreter on its own goroutine.
+//
+// Input instructions never block - if there are no packets waiting for a NIC, it receives -1.
+// The network is idle once every NIC has polled for input IdlePolls times
+// without any activity (any other input or output) in the whole network.
+//
+// There are no scheduler rounds, so delays of Routes are ignored.
+type ConcurrentNetwork struct {
+	Router    Router
+	NAT       NAT
+	IdlePolls int
+
+	nics     []*concurrentNIC
+	mu       sync.Mutex
+	activity atomic.Uint64
+	done     chan struct{}
+	errOnce  sync.Once
+	err      chan error
+}
+
+// NewConcurrentNetwork creates a network of NICs with a DirectRouter and the NAT at NATAddress.
+// NAT needs to be set before running the network.
+func NewConcurrentNetwork(nicCode *intcode.Interpreter, devices int) *ConcurrentNetwork {
+	n := &ConcurrentNetwork{
+		Router:    DirectRouter{NICs: devices, NATAddress: NATAddress},
+		IdlePolls: DefaultIdlePolls,
+		done:      make(chan struct{}),
+		err:       make(chan error, 1),
+	}
+
+	for i := 0; i < devices; i++ {
+		nic := &concurrentNIC{Interpreter: nicCode.Clone(), queue: deque.NewDeque[int]()}
+		nic.Input = make(chan int)
+		nic.Output = make(chan int)
+		nic.queue.PushBack(i)
+		n.nics = append(n.nics, nic)
+	}
+
+	return n
+}
+
+// fail records the first error encountered by any goroutine of the network
+func (n *ConcurrentNetwork) fail(err error) {
+	n.errOnce.Do(func() { n.err <- err })
+}
+
+// SendPacket routes a packet through the network
+func (n *ConcurrentNetwork) SendPacket(p Packet) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	r, err := n.Router.Route(p)
+	if err != nil {
+		return err
+	}
+
+	if r.NAT {
+		if n.NAT == nil {
+			return ErrNoNATAttached
+		}
+		n.NAT.Receive(p)
+	}
+
+	for _, addr := range r.NICs {
+		if addr < 0 || addr >= len(n.nics) {
+			return fmt.Errorf("%w: %d (from %d)", ErrUnknownDestination, addr, p.Src)
+		}
+		n.nics[addr].queue.PushBack(p.X)
+		n.nics[addr].queue.PushBack(p.Y)
+	}
+
+	n.activity.Add(1)
+	return nil
+}
+
+// feed supplies input to a NIC. Values are only removed from the queue after the NIC reads them,
+// so that a NIC never looks idle while a packet is in flight.
+func (n *ConcurrentNetwork) feed(nic *concurrentNIC) {
+	defer close(nic.Input)
+
+	for {
+		n.mu.Lock()
+		x, empty := -1, nic.queue.Len() == 0
+		if !empty {
+			x = nic.queue.PeekFront()
+		}
+		n.mu.Unlock()
+
+		select {
+		case nic.Input <- x:
+		case <-n.done:
+			return
+		}
+
+		if empty {
+			// Yield to other NICs, instead of busy-looping with this one
+			nic.polls.Add(1)
+			runtime.Gosched()
+		} else {
+			n.mu.Lock()
+			nic.queue.PopFront()
+			n.mu.Unlock()
+			n.activity.Add(1)
+		}
+	}
+}
+
+// exec runs a NIC until it halts, or until its input is closed after the network has stopped
+func (n *ConcurrentNetwork) exec(nic *concurrentNIC) {
+	defer func() {
+		if p := recover(); p != nil {
+			close(nic.Output)
+
+			if pErr, ok := p.(error); !ok || !errors.Is(pErr, intcode.ErrInputOverClosed) {
+				n.fail(fmt.Errorf("nic crashed: %v", p))
+			}
+		}
+	}()
+
+	nic.ExecAll()
+}
+
+// receive turns the output of a NIC into packets. Packets sent after the network has stopped are dropped.
+func (n *ConcurrentNetwork) receive(id int, nic *concurrentNIC) {
+	var values [3]int
+	received := 0
+
+	for x := range nic.Output {
+		n.activity.Add(1)
+		values[received] = x
+		received++
+
+		if received < 3 {
+			continue
+		}
+		received = 0
+
+		select {
+		case <-n.done:
+			continue
+		default:
+		}
+
+		p := Packet{Src: id, Dest: values[0], X: values[1], Y: values[2]}
+		if err := n.SendPacket(p); err != nil {
+			n.fail(err)
+		}
+	}
+
+	select {
+	case <-n.done:
+	default:
+		n.fail(fmt.Errorf("%w: %d", ErrNICHalted, id))
+	}
+}
+
+// waitIdle waits until every NIC has polled for input IdlePolls times. Returns true if there
+// was no activity in the meantime and no packets are waiting, false otherwise.
+func (n *ConcurrentNetwork) waitIdle() bool {
+	activity := n.activity.Load()
+	polls := make([]uint64, len(n.nics))
+	for id, nic := range n.nics {
+		polls[id] = nic.polls.Load()
+	}
+
+	for id, nic := range n.nics {
+		for nic.polls.Load() < polls[id]+uint64(n.IdlePolls) {
+			if n.activity.Load() != activity || len(n.err) > 0 {
+				return false
+			}
+			runtime.Gosched()
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, nic := range n.nics {
+		if nic.queue.Len() > 0 {
+			return false
+		}
+	}
+	return n.activity.Load() == activity
+}
+
+// Run runs the network until the NAT stops it, returning the result reported by the NAT.
+// All goroutines are stopped before returning.
+func (n *ConcurrentNetwork) Run() (Packet, error) {
+	if n.NAT == nil {
+		return Packet{}, ErrNoNATAttached
+	}
+
+	wg := &sync.WaitGroup{}
+	for id, nic := range n.nics {
+		wg.Add(3)
+		go func(nic *concurrentNIC) { defer wg.Done(); n.feed(nic) }(nic)
+		go func(nic *concurrentNIC) { defer wg.Done(); n.exec(nic) }(nic)
+		go func(id int, nic *concurrentNIC) { defer wg.Done(); n.receive(id, nic) }(id, nic)
+	}
+
+	result, err := n.monitor()
+	close(n.done)
+	wg.Wait()
+	return result, err
+}
+
+// monitor asks the NAT what to do every time the network becomes idle
+func (n *ConcurrentNetwork) monitor() (Packet, error) {
+	for {
+		select {
+		case err := <-n.err:
+			return Packet{}, err
+		default:
+		}
+
+		if !n.waitIdle() {
+			continue
+		}
+
+		n.mu.Lock()
+		send, stop, result := n.NAT.Idle()
+		n.mu.Unlock()
+
+		if stop {
+			return result, nil
+		} else if len(send) == 0 {
+			// NICs already receive -1 while idle, nothing else can wake the network up
+			return Packet{}, ErrNetworkStuck
+		}
+
+		for _, p := range send {
+			if err := n.SendPacket(p); err != nil {
+				return Packet{}, err
+			}
+		}
+	}
+}
+
+func solveConcurrent(r io.Reader, nat NAT) any {
+	nicCode := intcode.NewInterpreter(r)
+	network := NewConcurrentNetwork(nicCode, 50)
+	network.NAT = nat
+
+	p, err := network.Run()
+	if err != nil {
+		panic(err)
+	}
+	return p.Y
+}
+
+// SolveAConcurrent runs every NIC on its own goroutine
+func SolveAConcurrent(r io.Reader) any { return solveConcurrent(r, &FirstPacketNAT{}) }
+
+// SolveBConcurrent runs every NIC on its own goroutine
+func SolveBConcurrent(r io.Reader) any { return solveConcurrent(r, &MonitoringNAT{}) }
@@ -1,133 +1,228 @@
 package day23
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"runtime"
 
 	"github.com/MKuranowski/AdventOfCode2019/intcode"
-	"github.com/MKuranowski/AdventOfCode2019/util/deque"
-	"github.com/MKuranowski/AdventOfCode2019/util/set"
 )
 
-type Packet struct{ Dest, X, Y int }
+type Packet struct{ Src, Dest, X, Y int }
 
-type Network struct {
-	deviceByAddress map[int]*intcode.SyncInterpreter
+var (
+	ErrNICHalted     = errors.New("nic has turned itself off")
+	ErrNetworkStuck  = errors.New("network is idle and the nat didn't wake it up")
+	ErrNoNATAttached = errors.New("network has no nat")
+)
+
+type delayedPacket struct {
+	Packet
+	Route
+	due int // Scheduler round of the delivery
+}
 
-	readyDevices   deque.Deque[*intcode.SyncInterpreter]
-	blockedDevices set.Set[*intcode.SyncInterpreter]
+// Network simulates NICs running the same intcode program, with addresses equal to their indices.
+// Router decides where packets are delivered, and NAT decides what happens when the network is idle.
+type Network struct {
+	Router  Router
+	NAT     NAT
+	Capture *Capture // Optional, receives every sent packet
 
-	lastNatPacket Packet
+	s       *intcode.Scheduler
+	delayed []delayedPacket
+	err     error
 }
 
+// NewNetwork creates a network of NICs with a DirectRouter and the NAT at NATAddress.
+// NAT needs to be set before running the network.
 func NewNetwork(nicCode *intcode.SyncInterpreter, devices int) *Network {
 	n := &Network{
-		deviceByAddress: make(map[int]*intcode.SyncInterpreter),
-		readyDevices:    deque.NewDeque[*intcode.SyncInterpreter](),
-		blockedDevices:  make(set.Set[*intcode.SyncInterpreter]),
+		Router: DirectRouter{NICs: devices, NATAddress: NATAddress},
+		s:      intcode.NewScheduler(),
 	}
+	n.s.Output = n.handleOutput
 
 	for i := 0; i < devices; i++ {
 		d := nicCode.Clone()
 		d.Input.PushBack(i)
-
-		n.deviceByAddress[i] = d
-		n.readyDevices.PushBack(d)
+		n.s.Add(d)
 	}
 
 	return n
 }
 
-func (n *Network) SendPacket(p Packet) {
-	if p.Dest == 255 {
-		n.lastNatPacket = p
-	} else {
-		d := n.deviceByAddress[p.Dest]
-		d.Input.PushBack(p.X)
-		d.Input.PushBack(p.Y)
-
-		if n.blockedDevices.Has(d) {
-			n.blockedDevices.Remove(d)
-			n.readyDevices.PushBack(d)
+// SendPacket routes a packet through the network
+func (n *Network) SendPacket(p Packet) error {
+	if n.Capture != nil {
+		err := n.Capture.Write(CapturedPacket{Round: n.s.Round, Src: p.Src, Dest: p.Dest, X: p.X, Y: p.Y})
+		if err != nil {
+			return err
 		}
 	}
-}
 
-func (n *Network) RunUntilBlocked() Packet {
-	for n.readyDevices.Len() > 0 {
-		// Get a device that's ready
-		d := n.readyDevices.PopFront()
+	r, err := n.Router.Route(p)
+	if err != nil {
+		return err
+	} else if r.Delay > 0 {
+		n.delayed = append(n.delayed, delayedPacket{p, r, n.s.Round + r.Delay})
+		return nil
+	}
+	return n.deliver(p, r)
+}
 
-		// Execute it until it's blocked on input
-		state := d.ExecAll()
+func (n *Network) deliver(p Packet, r Route) error {
+	if r.NAT {
+		if n.NAT == nil {
+			return ErrNoNATAttached
+		}
+		n.NAT.Receive(p)
+	}
 
-		// Ensure state is BlockedOnInput
-		if state == intcode.SyncExecutionStateHalted {
-			panic("nic has turned itself off")
-		} else if state == intcode.SyncExecutionStateReady {
-			panic("intcode.SyncInterpreter.ExecAll() signalled ready")
+	for _, addr := range r.NICs {
+		if err := n.s.Send(addr, p.X, p.Y); errors.Is(err, intcode.ErrMachineHalted) {
+			return fmt.Errorf("%w: %d (packet from %d)", ErrNICHalted, addr, p.Src)
+		} else if err != nil {
+			return fmt.Errorf("%w: %d (from %d)", ErrUnknownDestination, addr, p.Src)
 		}
+	}
+	return nil
+}
 
-		// Move the device to blocked queue
-		n.blockedDevices.Add(d)
+// deliverDelayed delivers all delayed packets due in the current round. If force is set,
+// and no packets are due, the packets with the earliest due round are delivered instead.
+func (n *Network) deliverDelayed(force bool) error {
+	if len(n.delayed) == 0 {
+		return nil
+	}
 
-		// Process device outputs
-		for d.Output.Len() > 0 {
-			p := Packet{}
-			p.Dest = d.Output.PopFront()
-			p.X = d.Output.PopFront()
-			p.Y = d.Output.PopFront()
+	due := n.s.Round
+	if force {
+		earliest := n.delayed[0].due
+		for _, d := range n.delayed {
+			if d.due < earliest {
+				earliest = d.due
+			}
+		}
+		if earliest > due {
+			due = earliest
+		}
+	}
 
-			n.SendPacket(p)
+	remaining := n.delayed[:0]
+	var toDeliver []delayedPacket
+	for _, d := range n.delayed {
+		if d.due <= due {
+			toDeliver = append(toDeliver, d)
+		} else {
+			remaining = append(remaining, d)
 		}
 	}
+	n.delayed = remaining
 
-	return n.lastNatPacket
+	for _, d := range toDeliver {
+		if err := n.deliver(d.Packet, d.Route); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (n *Network) RunUntilNATPacket() Packet {
+func (n *Network) handleOutput(id int, d *intcode.SyncInterpreter) {
+	for n.err == nil && d.Output.Len() >= 3 {
+		p := Packet{Src: id}
+		p.Dest = d.Output.PopFront()
+		p.X = d.Output.PopFront()
+		p.Y = d.Output.PopFront()
+
+		n.err = n.SendPacket(p)
+	}
+}
+
+// runUntilIdle runs the NICs until all of them are waiting for input,
+// and there are no delayed packets
+func (n *Network) runUntilIdle() error {
 	for {
-		p := n.RunUntilBlocked()
-		if p.Dest == 255 {
-			return p
+		if err := n.deliverDelayed(n.s.Idle()); err != nil {
+			return err
+		}
+
+		if !n.s.Step() {
+			if len(n.delayed) == 0 {
+				return nil
+			}
+			continue
 		}
 
-		// Unblock the network by sending -1 to every receiver
-		for d := range n.blockedDevices {
-			d.Input.PushBack(-1)
-			n.readyDevices.PushBack(d)
-			delete(n.blockedDevices, d)
+		if n.err != nil {
+			return n.err
+		} else if halted := n.s.InState(intcode.MachineHalted); len(halted) > 0 {
+			return fmt.Errorf("%w: %d", ErrNICHalted, halted[0])
 		}
 	}
 }
 
-func (n *Network) RunWithNAT() Packet {
-	lastY := -1
+// Run runs the network until the NAT stops it, returning the result reported by the NAT
+func (n *Network) Run() (Packet, error) {
+	if n.NAT == nil {
+		return Packet{}, ErrNoNATAttached
+	}
 
 	for {
-		p := n.RunUntilNATPacket()
-		p.Dest = 0
+		if err := n.runUntilIdle(); err != nil {
+			return Packet{}, err
+		}
 
-		if lastY == p.Y {
-			return p
-		} else {
-			lastY = p.Y
-			n.SendPacket(p)
+		send, stop, result := n.NAT.Idle()
+		if stop {
+			return result, nil
+		}
+
+		for _, p := range send {
+			if err := n.SendPacket(p); err != nil {
+				return Packet{}, err
+			}
+		}
+
+		if len(send) == 0 {
+			// Unblock the network by sending -1 to every receiver
+			for _, id := range n.s.InState(intcode.MachineBlocked) {
+				n.s.Send(id, -1)
+			}
+		}
+
+		if n.s.Idle() && len(n.delayed) == 0 {
+			return Packet{}, ErrNetworkStuck
 		}
 	}
 }
 
-func SolveA(r io.Reader) any {
+func solve(r io.Reader, workers int, nat NAT, c *Capture) any {
 	nicCode := intcode.NewSyncInterpreter(r)
 	network := NewNetwork(nicCode, 50)
+	network.s.Workers = workers
+	network.NAT = nat
+	network.Capture = c
 
-	p := network.RunUntilNATPacket()
+	p, err := network.Run()
+	if err != nil {
+		panic(err)
+	}
 	return p.Y
 }
 
-func SolveB(r io.Reader) any {
-	nicCode := intcode.NewSyncInterpreter(r)
-	network := NewNetwork(nicCode, 50)
+func SolveA(r io.Reader) any { return solve(r, 1, &FirstPacketNAT{}, nil) }
+func SolveB(r io.Reader) any { return solve(r, 1, &MonitoringNAT{}, nil) }
 
-	p := network.RunWithNAT()
-	return p.Y
-}
+// SolveAParallel runs the NICs on all CPUs
+func SolveAParallel(r io.Reader) any { return solve(r, runtime.NumCPU(), &FirstPacketNAT{}, nil) }
+
+// SolveBParallel runs the NICs on all CPUs
+func SolveBParallel(r io.Reader) any { return solve(r, runtime.NumCPU(), &MonitoringNAT{}, nil) }
+
+// SolveACaptured works like SolveA, but additionally writes all packets to c
+func SolveACaptured(r io.Reader, c *Capture) any { return solve(r, 1, &FirstPacketNAT{}, c) }
+
+// SolveBCaptured works like SolveB, but additionally writes all packets to c
+func SolveBCaptured(r io.Reader, c *Capture) any { return solve(r, 1, &MonitoringNAT{}, c) }
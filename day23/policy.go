@@ -0,0 +1,116 @@
+package day23
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownDestination = errors.New("packet sent to an unknown address")
+
+// NATAddress is the address of the NAT used by the puzzle
+const NATAddress = 255
+
+// Route describes where a packet should be delivered
+type Route struct {
+	NICs  []int // Addresses of NICs receiving the packet
+	NAT   bool  // Whether the NAT receives the packet
+	Delay int   // Amount of scheduler rounds the delivery is delayed by
+}
+
+// Router decides where packets are delivered. A Route without any receivers drops the packet.
+type Router interface {
+	Route(p Packet) (Route, error)
+}
+
+// RouterFunc allows using ordinary functions as Routers
+type RouterFunc func(p Packet) (Route, error)
+
+func (f RouterFunc) Route(p Packet) (Route, error) { return f(p) }
+
+// DirectRouter delivers packets to NICs with addresses 0 to NICs-1, or to the NAT at NATAddress.
+type DirectRouter struct {
+	NICs       int
+	NATAddress int
+}
+
+func (r DirectRouter) Route(p Packet) (Route, error) {
+	if p.Dest == r.NATAddress {
+		return Route{NAT: true}, nil
+	} else if p.Dest < 0 || p.Dest >= r.NICs {
+		return Route{}, fmt.Errorf("%w: %d (from %d)", ErrUnknownDestination, p.Dest, p.Src)
+	}
+	return Route{NICs: []int{p.Dest}}, nil
+}
+
+// BroadcastRouter delivers packets sent to Address to all NICs (except the sender),
+// and routes all other packets with the wrapped Router.
+type BroadcastRouter struct {
+	Router
+	Address int
+	NICs    int
+}
+
+func (r BroadcastRouter) Route(p Packet) (Route, error) {
+	if p.Dest != r.Address {
+		return r.Router.Route(p)
+	}
+
+	route := Route{}
+	for addr := 0; addr < r.NICs; addr++ {
+		if addr != p.Src {
+			route.NICs = append(route.NICs, addr)
+		}
+	}
+	return route, nil
+}
+
+// NAT receives packets routed to it, and decides what happens when the whole network is idle,
+// that is when all NICs are waiting for input.
+type NAT interface {
+	Receive(p Packet)
+
+	// Idle returns packets which should be sent to wake the network up. If there are none,
+	// every NIC receives -1 (no packet) instead. If stop is set, the network stops with the provided result.
+	Idle() (send []Packet, stop bool, result Packet)
+}
+
+// FirstPacketNAT stops the network once it becomes idle after the NAT has received a packet,
+// with the first received packet as the result.
+type FirstPacketNAT struct {
+	first    Packet
+	received bool
+}
+
+func (n *FirstPacketNAT) Receive(p Packet) {
+	if !n.received {
+		n.first, n.received = p, true
+	}
+}
+
+func (n *FirstPacketNAT) Idle() ([]Packet, bool, Packet) { return nil, n.received, n.first }
+
+// MonitoringNAT resends the last received packet to address 0 whenever the network is idle.
+// It stops the network once it's about to send the same Y value twice in a row,
+// with the repeated packet as the result.
+type MonitoringNAT struct {
+	last     Packet
+	received bool
+	lastY    int
+	sent     bool
+}
+
+func (n *MonitoringNAT) Receive(p Packet) { n.last, n.received = p, true }
+
+func (n *MonitoringNAT) Idle() ([]Packet, bool, Packet) {
+	if !n.received {
+		return nil, false, Packet{}
+	}
+
+	p := Packet{Src: NATAddress, Dest: 0, X: n.last.X, Y: n.last.Y}
+	if n.sent && n.lastY == p.Y {
+		return nil, true, p
+	}
+
+	n.lastY, n.sent = p.Y, true
+	return []Packet{p}, false, Packet{}
+}
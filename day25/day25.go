@@ -2,13 +2,16 @@ package day25
 
 import (
 	"io"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/MKuranowski/AdventOfCode2019/day17"
 	"github.com/MKuranowski/AdventOfCode2019/intcode"
 	"github.com/MKuranowski/AdventOfCode2019/util/input"
 )
 
-// Mine map:
+// Mine map (Explorer maps out the ship automatically):
 //
 //  SCIENCE LAB--------CORRIDOR-------STABLES---------HOT CHOC.-------CREW QUART
 //                      (mutex)     (astrolabe)     (deh. water)       (wreath)
@@ -39,12 +42,141 @@ import (
 // - monolith
 // - astrolabe
 
-func SolveA(r io.Reader) any {
+// Room is a location on the ship, as described by the game
+type Room struct {
+	Name        string
+	Description string
+	Doors       []string
+	Items       []string
+}
+
+// ParseRoom parses the last room description from the game output
+func ParseRoom(output string) (r Room, ok bool) {
+	start := strings.LastIndex(output, "== ")
+	if start < 0 {
+		return r, false
+	}
+
+	var list *[]string
+	for idx, line := range strings.Split(output[start:], "\n") {
+		switch {
+		case idx == 0:
+			r.Name = strings.Trim(line, "= ")
+		case idx == 1:
+			r.Description = line
+		case line == "Doors here lead:":
+			list = &r.Doors
+		case line == "Items here:":
+			list = &r.Items
+		case strings.HasPrefix(line, "- ") && list != nil:
+			*list = append(*list, line[2:])
+		case line == "":
+			list = nil
+		}
+	}
+	return r, true
+}
+
+// AsciiOutput converts the output of the game into a string
+func AsciiOutput(output []int) string {
+	b := strings.Builder{}
+	for _, c := range output {
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
+
+// AsciiCommand converts a command into the input of the game
+func AsciiCommand(cmd string) (input []int) {
+	for _, c := range cmd {
+		input = append(input, int(c))
+	}
+	return append(input, '\n')
+}
+
+type searchState struct {
+	Room      Room
+	Inventory []string // Sorted
+}
+
+var passwordRegexp = regexp.MustCompile(`typing (\d+)`)
+
+// NewSearch prepares a search over the rooms of the ship and the items carried by the droid.
+// Dangerous items are not special-cased - taking them either halts the game,
+// loops forever (pruned by MaxSteps) or doesn't change the state (pruned as a duplicate).
+func NewSearch() *intcode.Search[searchState] {
+	return &intcode.Search[searchState]{
+		Strategy: intcode.SearchBFS,
+		MaxSteps: 100_000,
+		Moves: func(n *intcode.SearchNode[searchState]) (moves [][]int) {
+			for _, door := range n.State.Room.Doors {
+				moves = append(moves, AsciiCommand(door))
+			}
+			for _, item := range n.State.Room.Items {
+				moves = append(moves, AsciiCommand("take "+item))
+			}
+			return
+		},
+		Update: func(parent, child *intcode.SearchNode[searchState]) bool {
+			output := AsciiOutput(child.Output)
+			if room, ok := ParseRoom(output); ok {
+				child.State.Room = room
+			} else if move := AsciiOutput(child.Move); strings.HasPrefix(move, "take ") &&
+				strings.Contains(output, "You take the ") {
+				item := strings.TrimSuffix(strings.TrimPrefix(move, "take "), "\n")
+
+				child.State.Room.Items = nil
+				for _, roomItem := range parent.State.Room.Items {
+					if roomItem != item {
+						child.State.Room.Items = append(child.State.Room.Items, roomItem)
+					}
+				}
+
+				child.State.Inventory = append(append([]string(nil), parent.State.Inventory...), item)
+				sort.Strings(child.State.Inventory)
+			}
+			return true
+		},
+		Goal: func(n *intcode.SearchNode[searchState]) bool {
+			return passwordRegexp.MatchString(AsciiOutput(n.Output))
+		},
+		Key: func(n *intcode.SearchNode[searchState]) any {
+			return n.State.Room.Name + "|" + strings.Join(n.State.Inventory, ",")
+		},
+	}
+}
+
+// SolveASearch finds the password for the main airlock automatically,
+// by exploring every combination of room and carried items.
+func SolveASearch(r io.Reader) any {
+	// Get to the first room to know the initial state
+	m := intcode.NewSyncInterpreter(r)
+	m.ExecAll()
+	output := make([]int, 0, m.Output.Len())
+	for m.Output.Len() > 0 {
+		output = append(output, m.Output.PopFront())
+	}
+	room, _ := ParseRoom(AsciiOutput(output))
+
+	s := NewSearch()
+	s.MaxGoals = 1
+	result := s.Run(m, searchState{Room: room})
+	if len(result.Goals) == 0 {
+		panic("password not found")
+	}
+	return passwordRegexp.FindStringSubmatch(AsciiOutput(result.Goals[0].Output))[1]
+}
+
+func SolveA(r io.Reader) any { return SolveATraced(r, nil) }
+
+// SolveATraced plays the game just like SolveA,
+// reporting every instruction executed by the game to t.
+func SolveATraced(r io.Reader, t intcode.Tracer) any {
 	i := intcode.NewInterpreterNewIO(r)
 	s := day17.Screen{}
 
 	go input.AsciiStdinSender(i.Input, nil)
 	go s.Run(i.Output, nil)
-	i.ExecAll()
+	i.ExecAllTraced(t)
 	return s.LastNonASCII
 }
@@ -0,0 +1,436 @@
+package day25
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+	"github.com/MKuranowski/AdventOfCode2019/util/deque"
+)
+
+var (
+	ErrGameOver       = errors.New("game over")
+	ErrNoCheckpoint   = errors.New("security checkpoint not found")
+	ErrNoCombination  = errors.New("no combination of items passes the security checkpoint")
+	ErrUnexpectedText = errors.New("unexpected game output")
+)
+
+const (
+	SecurityCheckpoint = "Security Checkpoint"
+
+	// MaxCommandSteps is the maximum amount of instructions executed in response to a single command,
+	// exceeded by the infinite loop
+	MaxCommandSteps = 100_000
+)
+
+// Opposite returns the door leading back to the previous room
+func Opposite(door string) string {
+	switch door {
+	case "north":
+		return "south"
+	case "south":
+		return "north"
+	case "east":
+		return "west"
+	case "west":
+		return "east"
+	default:
+		panic(fmt.Errorf("invalid door: %q", door))
+	}
+}
+
+// Ship is the graph of discovered rooms
+type Ship struct {
+	Rooms map[string]Room
+	Doors map[string]map[string]string // Room name -> door -> room name behind the door
+}
+
+func NewShip() *Ship {
+	return &Ship{Rooms: make(map[string]Room), Doors: make(map[string]map[string]string)}
+}
+
+func (s *Ship) connect(from, door, to string) {
+	if s.Doors[from] == nil {
+		s.Doors[from] = make(map[string]string)
+	}
+	s.Doors[from][door] = to
+}
+
+// Route returns the shortest list of doors leading from one room to another,
+// or false if there's no known route.
+func (s *Ship) Route(from, to string) (doors []string, ok bool) {
+	type step struct{ room, door string }
+	prev := map[string]step{from: {}}
+	q := deque.NewDeque[string]()
+	q.PushBack(from)
+
+	for q.Len() > 0 {
+		room := q.PopFront()
+		if room == to {
+			for ; room != from; room = prev[room].room {
+				doors = append(doors, prev[room].door)
+			}
+			for i, j := 0, len(doors)-1; i < j; i, j = i+1, j-1 {
+				doors[i], doors[j] = doors[j], doors[i]
+			}
+			return doors, true
+		}
+
+		for _, door := range sortedKeys(s.Doors[room]) {
+			next := s.Doors[room][door]
+			if _, visited := prev[next]; !visited {
+				prev[next] = step{room, door}
+				q.PushBack(next)
+			}
+		}
+	}
+	return nil, false
+}
+
+// WriteTo lists the rooms, with their items and doors
+func (s *Ship) WriteTo(w io.Writer) (n int64, err error) {
+	b := &strings.Builder{}
+	for _, name := range sortedKeys(s.Rooms) {
+		fmt.Fprintf(b, "%s\n", name)
+		for _, item := range s.Rooms[name].Items {
+			fmt.Fprintf(b, "  item: %s\n", item)
+		}
+		for _, door := range sortedKeys(s.Doors[name]) {
+			fmt.Fprintf(b, "  %s: %s\n", door, s.Doors[name][door])
+		}
+	}
+	m, err := io.WriteString(w, b.String())
+	return int64(m), err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Explorer is a bot playing the text adventure: it maps out the ship, collects all items
+// which aren't traps, and finds the combination of items needed to pass the security checkpoint.
+type Explorer struct {
+	M    *intcode.SyncInterpreter
+	Ship *Ship
+	Room Room
+
+	Inventory []string
+	Traps     []string // Items which end or break the game, learned by trying them on a clone of M
+
+	// FloorDoor leads from the Security Checkpoint to the pressure-sensitive floor,
+	// which can only be entered with the right items
+	FloorDoor string
+
+	Log io.Writer // Receives all of the commands and game output, if not nil
+}
+
+// NewExplorer starts the game, stopping at the first room
+func NewExplorer(m *intcode.SyncInterpreter) (*Explorer, error) {
+	e := &Explorer{M: m, Ship: NewShip()}
+	output, err := e.run()
+	if err != nil {
+		return nil, err
+	}
+
+	room, ok := ParseRoom(output)
+	if !ok {
+		return nil, fmt.Errorf("%w: no room at the start: %q", ErrUnexpectedText, output)
+	}
+	e.enter(room)
+	return e, nil
+}
+
+// run executes the game until it asks for a command
+func (e *Explorer) run() (string, error) {
+	output, state, ok := execute(e.M)
+	e.log(output)
+	if !ok {
+		return output, fmt.Errorf("%w: game stuck in an infinite loop", ErrGameOver)
+	} else if state == intcode.SyncExecutionStateHalted {
+		return output, fmt.Errorf("%w: %s", ErrGameOver, lastSentence(output))
+	}
+	return output, nil
+}
+
+// execute runs the machine until it blocks on input or halts, returning false
+// if that takes more than MaxCommandSteps instructions.
+func execute(m *intcode.SyncInterpreter) (output string, state intcode.SyncExecutionState, ok bool) {
+	start := m.Steps
+	for state = intcode.SyncExecutionStateReady; state == intcode.SyncExecutionStateReady; {
+		if m.Steps-start > MaxCommandSteps {
+			return AsciiOutput(drain(m)), state, false
+		}
+		state = m.ExecOne()
+	}
+	return AsciiOutput(drain(m)), state, true
+}
+
+func drain(m *intcode.SyncInterpreter) (output []int) {
+	for m.Output.Len() > 0 {
+		output = append(output, m.Output.PopFront())
+	}
+	return
+}
+
+// lastSentence returns the last non-empty line of the output
+func lastSentence(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
+
+func (e *Explorer) log(s string) {
+	if e.Log != nil {
+		io.WriteString(e.Log, s)
+	}
+}
+
+// Command sends a command to the game, returning its response
+func (e *Explorer) Command(cmd string) (string, error) {
+	e.log(cmd + "\n")
+	for _, c := range AsciiCommand(cmd) {
+		e.M.Input.PushBack(c)
+	}
+	return e.run()
+}
+
+func (e *Explorer) enter(r Room) {
+	e.Room = r
+	e.Ship.Rooms[r.Name] = r
+}
+
+// Move goes through a door, updating the map
+func (e *Explorer) Move(door string) error {
+	from := e.Room.Name
+	output, err := e.Command(door)
+	if err != nil {
+		return err
+	}
+
+	room, ok := ParseRoom(output)
+	if !ok {
+		return fmt.Errorf("%w: can't go %s from %s: %q", ErrUnexpectedText, door, from, lastSentence(output))
+	}
+
+	if strings.Contains(output, "ejected back") {
+		e.FloorDoor = door
+	} else {
+		e.Ship.connect(from, door, room.Name)
+		e.Ship.connect(room.Name, Opposite(door), from)
+	}
+	e.enter(room)
+	return nil
+}
+
+// Take picks up an item from the current room
+func (e *Explorer) Take(item string) error {
+	output, err := e.Command("take " + item)
+	if err != nil {
+		return err
+	} else if !strings.Contains(output, "You take the "+item) {
+		return fmt.Errorf("%w: can't take %s: %q", ErrUnexpectedText, item, lastSentence(output))
+	}
+
+	e.Room.Items = without(e.Room.Items, item)
+	e.Ship.Rooms[e.Room.Name] = e.Room
+	e.Inventory = append(e.Inventory, item)
+	sort.Strings(e.Inventory)
+	return nil
+}
+
+// Drop leaves an item in the current room
+func (e *Explorer) Drop(item string) error {
+	output, err := e.Command("drop " + item)
+	if err != nil {
+		return err
+	} else if !strings.Contains(output, "You drop the "+item) {
+		return fmt.Errorf("%w: can't drop %s: %q", ErrUnexpectedText, item, lastSentence(output))
+	}
+
+	e.Room.Items = append(e.Room.Items, item)
+	e.Ship.Rooms[e.Room.Name] = e.Room
+	e.Inventory = without(e.Inventory, item)
+	return nil
+}
+
+func without(items []string, item string) (rest []string) {
+	for _, i := range items {
+		if i != item {
+			rest = append(rest, i)
+		}
+	}
+	return
+}
+
+// IsTrap checks, on a clone of the game, whether taking an item ends the game
+// or prevents the droid from moving.
+func (e *Explorer) IsTrap(item string) bool {
+	clone := &Explorer{M: e.M.Clone(), Ship: NewShip(), Room: e.Room}
+	if err := clone.Take(item); err != nil {
+		return true
+	}
+	return clone.Move(e.Room.Doors[0]) != nil
+}
+
+// Explore visits every room reachable from the current one in a depth-first order,
+// taking all items which aren't traps, and returns to the current room.
+func (e *Explorer) Explore() error {
+	for _, item := range e.Room.Items {
+		if e.IsTrap(item) {
+			e.Traps = append(e.Traps, item)
+		} else if err := e.Take(item); err != nil {
+			return err
+		}
+	}
+
+	for _, door := range e.Room.Doors {
+		if _, known := e.Ship.Doors[e.Room.Name][door]; known || (door == e.FloorDoor && e.Room.Name == SecurityCheckpoint) {
+			continue
+		}
+
+		from, known := e.Room.Name, len(e.Ship.Rooms)
+		if err := e.Move(door); err != nil {
+			return err
+		} else if e.Room.Name == from {
+			// Ejected from the pressure-sensitive floor
+			continue
+		}
+
+		// Rooms are only explored when entered for the first time
+		if len(e.Ship.Rooms) > known {
+			if err := e.Explore(); err != nil {
+				return err
+			}
+		}
+
+		if err := e.Move(Opposite(door)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GoTo walks to the provided room, using the shortest known route
+func (e *Explorer) GoTo(room string) error {
+	doors, ok := e.Ship.Route(e.Room.Name, room)
+	if !ok {
+		return fmt.Errorf("no known route from %s to %s", e.Room.Name, room)
+	}
+
+	for _, door := range doors {
+		if err := e.Move(door); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PassCheckpoint tries combinations of the carried items on the pressure-sensitive floor,
+// until the droid has the right weight, and returns the password for the main airlock.
+// Combinations which are supersets of a too heavy combination, or subsets of a too light one, are skipped.
+func (e *Explorer) PassCheckpoint() (password string, err error) {
+	if e.Room.Name != SecurityCheckpoint || e.FloorDoor == "" {
+		return "", ErrNoCheckpoint
+	}
+
+	items := append([]string(nil), e.Inventory...)
+	var heavy, light []uint
+
+	// Trying small combinations first lets too heavy ones rule out the most of the remaining combinations
+	combinations := make([]uint, 1<<len(items))
+	for idx := range combinations {
+		combinations[idx] = uint(idx)
+	}
+	sort.SliceStable(combinations, func(i, j int) bool {
+		return bits.OnesCount(combinations[i]) < bits.OnesCount(combinations[j])
+	})
+
+	for _, combination := range combinations {
+		if dominated(combination, heavy, light) {
+			continue
+		}
+
+		for idx, item := range items {
+			carried := contains(e.Inventory, item)
+			if wanted := combination&(1<<idx) != 0; wanted && !carried {
+				err = e.Take(item)
+			} else if !wanted && carried {
+				err = e.Drop(item)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		var output string
+		output, err = e.Command(e.FloorDoor)
+		if match := passwordRegexp.FindStringSubmatch(output); match != nil {
+			return match[1], nil
+		} else if err != nil {
+			return "", err
+		}
+
+		switch {
+		case strings.Contains(output, "Droids on this ship are lighter"):
+			heavy = append(heavy, combination)
+		case strings.Contains(output, "Droids on this ship are heavier"):
+			light = append(light, combination)
+		default:
+			return "", fmt.Errorf("%w: no weight feedback: %q", ErrUnexpectedText, lastSentence(output))
+		}
+	}
+	return "", ErrNoCombination
+}
+
+// dominated returns true if a combination of items contains all items of a too heavy combination,
+// or is contained in a too light combination
+func dominated(combination uint, heavy, light []uint) bool {
+	for _, h := range heavy {
+		if combination&h == h {
+			return true
+		}
+	}
+	for _, l := range light {
+		if combination&l == combination {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// SolveAExplore finds the password for the main airlock with an Explorer
+func SolveAExplore(r io.Reader) any {
+	e, err := NewExplorer(intcode.NewSyncInterpreter(r))
+	if err != nil {
+		panic(err)
+	}
+
+	if err := e.Explore(); err != nil {
+		panic(err)
+	} else if err := e.GoTo(SecurityCheckpoint); err != nil {
+		panic(err)
+	}
+
+	password, err := e.PassCheckpoint()
+	if err != nil {
+		panic(err)
+	}
+	return password
+}
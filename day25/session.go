@@ -0,0 +1,167 @@
+package day25
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+// SessionHelp describes the meta-commands understood by a Session
+const SessionHelp = `Meta-commands:
+  !save NAME  remember the current state of the game
+  !load NAME  go back to a remembered state
+  !undo       revert the last command (or !load)
+  !saves      list remembered states
+  !help       show this message
+`
+
+// Session is an interactive game of the text adventure. Commands starting with '!' are handled
+// by the session itself; save points and undo are backed by clones of the game.
+type Session struct {
+	M *intcode.SyncInterpreter
+	W io.Writer // Receives the game output, and echoed commands from scripts
+
+	// Transcript receives everything shown to the player, and all of the commands (including typed ones),
+	// if not nil
+	Transcript io.Writer
+
+	Over bool // The game has halted or got stuck; it can only be continued with !undo or !load
+
+	saves   map[string]*intcode.SyncInterpreter // Only games waiting for a command are saved
+	history []sessionState                      // Game before every command (or !load), for !undo
+}
+
+type sessionState struct {
+	m    *intcode.SyncInterpreter
+	over bool
+}
+
+// NewSession prepares a session, without running the game yet
+func NewSession(m *intcode.SyncInterpreter, w io.Writer) *Session {
+	return &Session{M: m, W: w, saves: make(map[string]*intcode.SyncInterpreter)}
+}
+
+func (s *Session) print(text string) {
+	io.WriteString(s.W, text)
+	if s.Transcript != nil {
+		io.WriteString(s.Transcript, text)
+	}
+}
+
+// Start runs the game until it asks for the first command
+func (s *Session) Start() { s.run() }
+
+func (s *Session) run() {
+	output, state, ok := execute(s.M)
+	s.print(output)
+
+	switch {
+	case !ok:
+		s.print("\n[the game got stuck in an infinite loop]\n")
+		s.Over = true
+	case state == intcode.SyncExecutionStateHalted:
+		s.print("\n[the game has ended]\n")
+		s.Over = true
+	}
+}
+
+// Command handles a single line of input. If echo is set, the line is also shown to the player
+// (as it wasn't typed in), otherwise it's only written to the transcript.
+func (s *Session) Command(line string, echo bool) {
+	line = strings.TrimSpace(line)
+	if echo {
+		s.print(line + "\n")
+	} else if s.Transcript != nil {
+		io.WriteString(s.Transcript, line+"\n")
+	}
+
+	if line == "" {
+		return
+	} else if strings.HasPrefix(line, "!") {
+		s.meta(line)
+		return
+	} else if s.Over {
+		s.print("[the game is over; !undo or !load to continue]\n")
+		return
+	}
+
+	s.history = append(s.history, sessionState{s.M.Clone(), false})
+	for _, c := range AsciiCommand(line) {
+		s.M.Input.PushBack(c)
+	}
+	s.run()
+}
+
+func (s *Session) meta(line string) {
+	cmd, name, _ := strings.Cut(line, " ")
+	name = strings.TrimSpace(name)
+
+	switch {
+	case cmd == "!save" && name != "":
+		if s.Over {
+			s.print("[the game is over; there's nothing to save]\n")
+			return
+		}
+		s.saves[name] = s.M.Clone()
+		s.print(fmt.Sprintf("[saved %q]\n", name))
+
+	case cmd == "!load" && name != "":
+		saved, ok := s.saves[name]
+		if !ok {
+			s.print(fmt.Sprintf("[no save point %q]\n", name))
+			return
+		}
+		s.history = append(s.history, sessionState{s.M, s.Over})
+		s.restore(sessionState{saved.Clone(), false})
+		s.print(fmt.Sprintf("[loaded %q]\n", name))
+
+	case cmd == "!undo":
+		if len(s.history) == 0 {
+			s.print("[nothing to undo]\n")
+			return
+		}
+		last := s.history[len(s.history)-1]
+		s.history = s.history[:len(s.history)-1]
+		s.restore(last)
+		s.print("[undone]\n")
+
+	case cmd == "!saves":
+		names := sortedKeys(s.saves)
+		if len(names) == 0 {
+			s.print("[no save points]\n")
+		} else {
+			s.print("[" + strings.Join(names, ", ") + "]\n")
+		}
+
+	default:
+		s.print(SessionHelp)
+	}
+}
+
+// restore replaces the game with a remembered one. Games which weren't over are waiting for
+// a command, as Clone doesn't copy pending I/O; games which were over (also the ones stuck
+// in an infinite loop) stay over.
+func (s *Session) restore(st sessionState) {
+	s.M, s.Over = st.m, st.over
+}
+
+// Script executes commands from the provided reader, one per line, echoing them to the player.
+// Lines starting with '#' are comments.
+func (s *Session) Script(r io.Reader) {
+	for _, line := range input.ReadLines(r) {
+		if !strings.HasPrefix(line, "#") {
+			s.Command(line, true)
+		}
+	}
+}
+
+// Interact reads commands typed by the player, until the end of input
+func (s *Session) Interact(r io.Reader) {
+	lines := input.NewLineIterator(r)
+	for lines.Next() {
+		s.Command(lines.Get(), false)
+	}
+}
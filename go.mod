@@ -2,4 +2,9 @@ module github.com/MKuranowski/AdventOfCode2019
 
 go 1.19
 
-require golang.org/x/exp v0.0.0-20220921164117-439092de6870
+require (
+	golang.org/x/exp v0.0.0-20220921164117-439092de6870
+	golang.org/x/term v0.5.0
+)
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -0,0 +1,184 @@
+package intcode
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// callWindow is the maximum amount of steps between storing a return address and
+// the jump into a routine, for the jump to be considered a call
+const callWindow = 8
+
+// Frame is a single entry of a reconstructed call stack
+type Frame struct {
+	Entry        int // Address of the first instruction of the routine
+	CallIP       int // Address of the jump which called the routine, -1 for the outermost frame
+	Return       int // Address where the routine returns to, -1 for the outermost frame
+	RelativeBase int // Relative base at the time of the call
+	Step         int // Step of the call
+
+	path string // Entries of all routines on the stack, as used in folded stacks
+}
+
+// RoutineStats describes how much time the program spent in a routine
+type RoutineStats struct {
+	Entry int
+	Calls int
+	Self  int // Amount of instructions executed in the routine itself, excluding its callees
+}
+
+type storedAddress struct {
+	value int
+	step  int
+}
+
+// CallStack is a Tracer which reconstructs the call stack of a program.
+//
+// Intcode has no call instructions. Instead, programs store the return address
+// (usually relative to the relative base, which acts as a stack pointer) and jump to the routine.
+// A taken jump is treated as a call if the address right after the jump was written to memory
+// in the last few steps. A taken jump to the return address of a frame on the stack
+// is treated as a return from that frame (and all frames above it).
+type CallStack struct {
+	Frames   []Frame // Currently active frames, the outermost first
+	Routines map[int]*RoutineStats
+	Folded   map[string]int // Instructions executed per call path, see WriteFolded
+
+	// W optionally receives a backtrace if the program panics
+	W io.Writer
+
+	lastIP int
+	recent [4]storedAddress
+	next   int
+}
+
+func NewCallStack() *CallStack {
+	return &CallStack{
+		Frames:   []Frame{{Entry: 0, CallIP: -1, Return: -1, path: "0"}},
+		Routines: map[int]*RoutineStats{0: {Entry: 0, Calls: 1}},
+		Folded:   make(map[string]int),
+	}
+}
+
+// Top returns the innermost frame
+func (c *CallStack) Top() *Frame { return &c.Frames[len(c.Frames)-1] }
+
+func (c *CallStack) Trace(e TraceEntry) {
+	c.lastIP = e.IP
+	top := c.Top()
+	c.Routines[top.Entry].Self++
+	c.Folded[top.path]++
+
+	if e.Write >= 0 {
+		c.recent[c.next] = storedAddress{e.NewValue, e.Step}
+		c.next = (c.next + 1) % len(c.recent)
+	}
+
+	isJump := e.Instruction.Op == OpJumpIfTrue || e.Instruction.Op == OpJumpIfFalse
+	fallthroughIP := e.IP + e.Instruction.Size()
+	if !isJump || e.NextIP == fallthroughIP {
+		return
+	}
+
+	if c.returnAddressStored(fallthroughIP, e.Step) {
+		c.push(Frame{
+			Entry:        e.NextIP,
+			CallIP:       e.IP,
+			Return:       fallthroughIP,
+			RelativeBase: e.RelativeBase,
+			Step:         e.Step,
+		})
+		return
+	}
+
+	for idx := len(c.Frames) - 1; idx > 0; idx-- {
+		if c.Frames[idx].Return == e.NextIP {
+			c.Frames = c.Frames[:idx]
+			return
+		}
+	}
+}
+
+func (c *CallStack) returnAddressStored(addr, step int) bool {
+	for _, s := range c.recent {
+		if s.value == addr && step-s.step <= callWindow {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CallStack) push(f Frame) {
+	f.path = c.Top().path + ";" + strconv.Itoa(f.Entry)
+	c.Frames = append(c.Frames, f)
+
+	stats, ok := c.Routines[f.Entry]
+	if !ok {
+		stats = &RoutineStats{Entry: f.Entry}
+		c.Routines[f.Entry] = stats
+	}
+	stats.Calls++
+}
+
+func (c *CallStack) TracePanic(ip int, p any) {
+	if c.W != nil {
+		fmt.Fprintf(c.W, "panic at %d: %v\n", ip, p)
+		c.lastIP = ip
+		c.WriteBacktrace(c.W)
+	}
+}
+
+// WriteBacktrace writes the frames of the stack, the innermost first,
+// along with the address of the current instruction in every frame.
+func (c *CallStack) WriteBacktrace(w io.Writer) error {
+	b := &strings.Builder{}
+	ip := c.lastIP
+	for idx := len(c.Frames) - 1; idx >= 0; idx-- {
+		f := c.Frames[idx]
+		fmt.Fprintf(b, "#%-3d routine %-6d at %d", len(c.Frames)-1-idx, f.Entry, ip)
+		if f.CallIP >= 0 {
+			fmt.Fprintf(b, ", called at step %d with rb=%d", f.Step, f.RelativeBase)
+		}
+		b.WriteByte('\n')
+		ip = f.CallIP
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Profile returns the statistics of all called routines, from the most executed instructions
+func (c *CallStack) Profile() []RoutineStats {
+	profile := make([]RoutineStats, 0, len(c.Routines))
+	for _, r := range c.Routines {
+		profile = append(profile, *r)
+	}
+	sort.Slice(profile, func(i, j int) bool {
+		if profile[i].Self != profile[j].Self {
+			return profile[i].Self > profile[j].Self
+		}
+		return profile[i].Entry < profile[j].Entry
+	})
+	return profile
+}
+
+// WriteFolded writes the executed instruction counts per call path in the "folded stacks" format,
+// understood by flame graph tools: "0;120;455 1234", with routines identified by their entry address.
+func (c *CallStack) WriteFolded(w io.Writer) error {
+	paths := make([]string, 0, len(c.Folded))
+	for path := range c.Folded {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	b := &strings.Builder{}
+	for _, path := range paths {
+		fmt.Fprintf(b, "%s %d\n", path, c.Folded[path])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,87 @@
+package intcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// callStackProgram is the compiled version of:
+//
+//	func g(x) { return x * 2; }              // entry 10
+//	func f(x) { return g(x) + 1; }           // entry 28
+//	func main() { out(f(in())); out(f(5)); } // entry 65
+const callStackProgram = "109,116,21101,9,0,0,1105,1,65,99,21202,2,2,3,21201,3,0,1,2105,1,0," +
+	"21101,0,0,1,2105,1,0,21201,2,0,7,21101,41,0,5,109,5,1105,1,10,109,-5,21201,6,0,3,21201,3," +
+	"1,4,21201,4,0,1,2105,1,0,21101,0,0,1,2105,1,0,203,2,21201,2,0,6,21101,80,0,4,109,4,1105,1," +
+	"28,109,-4,21201,5,0,3,204,3,21101,5,0,6,21101,101,0,4,109,4,1105,1,28,109,-4,21201,5,0,2," +
+	"204,2,21101,0,0,1,2105,1,0"
+
+func TestCallStack(t *testing.T) {
+	i := NewSyncInterpreter(strings.NewReader(callStackProgram))
+	i.Input.PushBack(20)
+
+	c := NewCallStack()
+	var inG [][]int
+	checkG := TracerFunc(func(e TraceEntry) {
+		if e.IP == 10 {
+			entries := []int{}
+			for _, f := range c.Frames {
+				entries = append(entries, f.Entry)
+			}
+			inG = append(inG, entries)
+		}
+	})
+
+	if state := i.ExecAllTraced(Tracers{c, checkG}); state != SyncExecutionStateHalted {
+		t.Fatalf("program did not halt: %v", state)
+	}
+
+	expected := [][]int{{0, 65, 28, 10}, {0, 65, 28, 10}}
+	if !reflect.DeepEqual(inG, expected) {
+		t.Errorf("got stacks %v in g, expected %v", inG, expected)
+	}
+
+	if len(c.Frames) != 1 {
+		t.Errorf("expected only the outermost frame after halting, got %v", c.Frames)
+	}
+
+	for entry, calls := range map[int]int{65: 1, 28: 2, 10: 2} {
+		if got := c.Routines[entry]; got == nil || got.Calls != calls {
+			t.Errorf("routine %d: got %v, expected %d calls", entry, got, calls)
+		}
+	}
+
+	total := 0
+	for _, r := range c.Profile() {
+		total += r.Self
+	}
+	if total != i.Steps {
+		t.Errorf("profile covers %d instructions, expected %d", total, i.Steps)
+	}
+}
+
+func TestCallStackPanic(t *testing.T) {
+	i := NewSyncInterpreter(strings.NewReader(callStackProgram))
+	i.Memory[10] = 98 // Unknown opcode at the start of g
+	i.Input.PushBack(20)
+
+	b := &strings.Builder{}
+	c := NewCallStack()
+	c.W = b
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		i.ExecAllTraced(c)
+	}()
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "panic at 10:") ||
+		!strings.Contains(lines[1], "routine 10 ") || !strings.Contains(lines[4], "routine 0 ") {
+		t.Errorf("unexpected backtrace:\n%s", b.String())
+	}
+}
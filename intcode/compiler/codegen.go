@@ -0,0 +1,735 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+)
+
+// Compile translates a program into a relocatable intcode object.
+//
+// The language has integer variables, arrays, if/else, while and functions:
+//
+//	var total;
+//	var seen[16];
+//
+//	func square(x) { return x * x; }
+//
+//	func main() {
+//		var n = in();
+//		while (n != 0) {
+//			total = total + square(n);
+//			n = in();
+//		}
+//		out(total);
+//	}
+//
+// Available operators, loosest first, are: ||, &&, == !=, < <= > >=, + -, *, and unary ! -.
+// Intcode has no division, and neither does the language. Builtins in() and out(x)
+// read and write a single value. Calls to functions which are not defined in the source
+// become imports, and all defined functions are exported.
+//
+// Every function call gets a frame on a stack addressed with the relative base:
+// [rb+0] holds the return address, [rb+1] the return value, followed by the parameters,
+// the local variables (arrays take a cell for every element) and the temporaries.
+// Arrays start zeroed; local ones are cleared on every declaration. If the source defines main,
+// the object starts with code which sets up the stack after the end of the linked program,
+// calls main and halts; such an object must be linked first.
+func Compile(name string, r io.Reader) (*intcode.Object, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := lex(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parse(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return generate(name, p)
+}
+
+// label is a position in the generated code or data, -1 until placed
+type label struct{ addr int }
+
+func newLabel() *label { return &label{-1} }
+
+// operand is a parameter of an instruction
+type operand struct {
+	Mode   intcode.Mode
+	Value  int
+	Label  *label // Address of the label is added to Value
+	Symbol string // Address of the imported symbol is added to Value
+	Frame  int    // Multiple of the current function's frame size added to Value
+}
+
+func imm(x int) operand { return operand{Mode: intcode.ModeImmediate, Value: x} }
+func rel(x int) operand { return operand{Mode: intcode.ModeRelative, Value: x} }
+
+// addrOf is the address of l, as an immediate value
+func addrOf(l *label) operand { return operand{Mode: intcode.ModeImmediate, Label: l} }
+
+// at is the memory cell at l
+func at(l *label) operand { return operand{Mode: intcode.ModePosition, Label: l} }
+
+func (o operand) isConst() bool {
+	return o.Mode == intcode.ModeImmediate && o.Label == nil && o.Symbol == "" && o.Frame == 0
+}
+
+type fixup struct {
+	offset int
+	label  *label
+}
+
+type frameFixup struct {
+	offset int
+	mul    int
+}
+
+type global struct {
+	decl *varDecl
+	l    *label
+}
+
+type function struct {
+	decl *funcDecl
+	l    *label
+}
+
+// local is a variable in the frame of the function being generated
+type local struct {
+	offset int // From the relative base
+	size   int // Amount of elements for arrays, 0 for scalars
+}
+
+// array is a global or local array
+type array struct {
+	name string
+	size int
+	base operand // The first element
+}
+
+// addr is the address of the element at idx as an immediate value,
+// relative to the relative base for local arrays
+func (a array) addr(idx int) operand {
+	return operand{Mode: intcode.ModeImmediate, Value: a.base.Value + idx, Label: a.base.Label}
+}
+
+type generator struct {
+	obj     *intcode.Object
+	fixups  []fixup
+	globals map[string]global
+	funcs   map[string]function
+	imports map[string]bool
+
+	// State of the function being generated
+	scopes      []map[string]local
+	nextLocal   int
+	tempBase    int
+	temps       int
+	maxTemps    int
+	frameFixups []frameFixup
+}
+
+func generate(name string, p *program) (obj *intcode.Object, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok {
+				err = e
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	g := &generator{
+		obj:     intcode.NewObject(name),
+		globals: make(map[string]global),
+		funcs:   make(map[string]function),
+		imports: make(map[string]bool),
+	}
+	g.declare(p)
+
+	if main, ok := g.funcs["main"]; ok {
+		g.prologue(main)
+	}
+
+	for _, f := range p.Funcs {
+		g.function(g.funcs[f.Name])
+	}
+
+	for _, gl := range p.Globals {
+		g.place(g.globals[gl.Name].l)
+		if gl.Size > 0 {
+			g.obj.Code = append(g.obj.Code, make([]int, gl.Size)...)
+		} else if gl.Init == nil {
+			g.obj.Code = append(g.obj.Code, 0)
+		} else if v, ok := constValue(gl.Init); ok {
+			g.obj.Code = append(g.obj.Code, v)
+		} else {
+			fail(gl.Init.pos(), "initial value of %s is not a constant", gl.Name)
+		}
+	}
+
+	for _, f := range g.fixups {
+		g.obj.Code[f.offset] += f.label.addr
+	}
+
+	return g.obj, g.obj.Validate()
+}
+
+func fail(at token, format string, args ...any) {
+	panic(&Error{at.Line, at.Col, fmt.Sprintf(format, args...)})
+}
+
+func isBuiltin(name string) bool { return name == "in" || name == "out" }
+
+// declare collects all globals and functions, so that they can be used before their definition
+func (g *generator) declare(p *program) {
+	defined := func(tok token, name string) {
+		_, isGlobal := g.globals[name]
+		_, isFunc := g.funcs[name]
+		if isGlobal || isFunc {
+			fail(tok, "%s redeclared", name)
+		} else if isBuiltin(name) || name == intcode.EndSymbol {
+			fail(tok, "%s is reserved", name)
+		}
+	}
+
+	for _, d := range p.Globals {
+		defined(d.Tok, d.Name)
+		g.globals[d.Name] = global{d, newLabel()}
+	}
+
+	for _, f := range p.Funcs {
+		defined(f.Tok, f.Name)
+		g.funcs[f.Name] = function{f, newLabel()}
+		if f.Name == "main" && len(f.Params) > 0 {
+			fail(f.Tok, "main can't take parameters")
+		}
+	}
+}
+
+func (g *generator) here() int { return len(g.obj.Code) }
+
+func (g *generator) place(l *label) { l.addr = g.here() }
+
+func (g *generator) emit(op intcode.Opcode, args ...operand) {
+	code, mul := int(op), 100
+	for _, a := range args {
+		code += int(a.Mode) * mul
+		mul *= 10
+	}
+	g.obj.Code = append(g.obj.Code, code)
+
+	for _, a := range args {
+		offset := g.here()
+		g.obj.Code = append(g.obj.Code, a.Value)
+
+		if a.Label != nil {
+			g.fixups = append(g.fixups, fixup{offset, a.Label})
+			g.obj.Relocations = append(g.obj.Relocations, intcode.Relocation{Offset: offset})
+		}
+		if a.Symbol != "" {
+			g.obj.Relocations = append(g.obj.Relocations, intcode.Relocation{Offset: offset, Symbol: a.Symbol})
+		}
+		if a.Frame != 0 {
+			g.frameFixups = append(g.frameFixups, frameFixup{offset, a.Frame})
+		}
+	}
+}
+
+func (g *generator) move(src, dst operand) {
+	if src != dst {
+		g.emit(intcode.OpAdd, src, imm(0), dst)
+	}
+}
+
+func (g *generator) jump(l *label) { g.emit(intcode.OpJumpIfTrue, imm(1), addrOf(l)) }
+
+func (g *generator) importSymbol(sym string) {
+	if !g.imports[sym] {
+		g.imports[sym] = true
+		g.obj.Imports = append(g.obj.Imports, sym)
+	}
+}
+
+// prologue sets up the stack right after the end of the program, calls main and halts
+func (g *generator) prologue(main function) {
+	g.importSymbol(intcode.EndSymbol)
+	ret := newLabel()
+
+	g.emit(intcode.OpAdjustRelativeBase, operand{Mode: intcode.ModeImmediate, Symbol: intcode.EndSymbol})
+	g.move(addrOf(ret), rel(0))
+	g.jump(main.l)
+	g.place(ret)
+	g.emit(intcode.OpHalt)
+}
+
+func (g *generator) function(f function) {
+	g.place(f.l)
+	g.obj.Exports[f.decl.Name] = f.l.addr
+
+	g.scopes = []map[string]local{{}}
+	for i, param := range f.decl.Params {
+		if _, dup := g.scopes[0][param]; dup {
+			fail(f.decl.Tok, "duplicate parameter %s", param)
+		}
+		g.scopes[0][param] = local{offset: 2 + i}
+	}
+
+	g.nextLocal = 2 + len(f.decl.Params)
+	g.tempBase = g.nextLocal + countLocals(f.decl.Body)
+	g.temps, g.maxTemps = 0, 0
+	g.frameFixups = g.frameFixups[:0]
+
+	g.stmts(f.decl.Body)
+	g.ret(imm(0))
+
+	frameSize := g.tempBase + g.maxTemps
+	for _, f := range g.frameFixups {
+		g.obj.Code[f.offset] += f.mul * frameSize
+	}
+}
+
+func countLocals(stmts []stmt) (n int) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *varDecl:
+			if s.Size > 0 {
+				n += s.Size
+			} else {
+				n++
+			}
+		case *ifStmt:
+			n += countLocals(s.Then) + countLocals(s.Else)
+		case *whileStmt:
+			n += countLocals(s.Body)
+		}
+	}
+	return
+}
+
+func (g *generator) temp() operand {
+	t := rel(g.tempBase + g.temps)
+	g.temps++
+	if g.temps > g.maxTemps {
+		g.maxTemps = g.temps
+	}
+	return t
+}
+
+func (g *generator) isTemp(o operand) bool {
+	return o.Mode == intcode.ModeRelative && o.Frame == 0 && o.Value >= g.tempBase
+}
+
+// stable ensures that o won't be changed by evaluating further expressions
+func (g *generator) stable(o operand) operand {
+	if o.isConst() || g.isTemp(o) {
+		return o
+	}
+	t := g.temp()
+	g.move(o, t)
+	return t
+}
+
+func (g *generator) variable(tok token, name string) operand {
+	for i := len(g.scopes) - 1; i >= 0; i-- {
+		if l, ok := g.scopes[i][name]; ok {
+			if l.size > 0 {
+				fail(tok, "%s is an array", name)
+			}
+			return rel(l.offset)
+		}
+	}
+
+	if gl, ok := g.globals[name]; ok {
+		if gl.decl.Size > 0 {
+			fail(tok, "%s is an array", name)
+		}
+		return at(gl.l)
+	}
+
+	fail(tok, "undefined variable %s", name)
+	return operand{}
+}
+
+func (g *generator) array(tok token, name string) array {
+	for i := len(g.scopes) - 1; i >= 0; i-- {
+		if l, ok := g.scopes[i][name]; ok {
+			if l.size == 0 {
+				fail(tok, "%s is not an array", name)
+			}
+			return array{name, l.size, rel(l.offset)}
+		}
+	}
+
+	gl, ok := g.globals[name]
+	if !ok || gl.decl.Size == 0 {
+		fail(tok, "%s is not an array", name)
+	}
+	return array{name, gl.decl.Size, at(gl.l)}
+}
+
+// element returns the array element at a constant index, checking the bounds
+func element(tok token, a array, idx int) operand {
+	if idx < 0 || idx >= a.size {
+		fail(tok, "index %d out of bounds of %s[%d]", idx, a.name, a.size)
+	}
+	return operand{Mode: a.base.Mode, Value: a.base.Value + idx, Label: a.base.Label}
+}
+
+// indexed returns an element at an index only known at runtime: its address (or offset from
+// the relative base) is written into the parameter of the next instruction, which is returned.
+// The parameter is the n-th one of that instruction.
+func (g *generator) indexed(a array, idx operand, n int) operand {
+	param := newLabel()
+	g.emit(intcode.OpAdd, a.addr(0), idx, at(param))
+	param.addr = g.here() + n
+	return operand{Mode: a.base.Mode}
+}
+
+// clear zeroes all elements of a local array, with a loop
+func (g *generator) clear(a array) {
+	i, more := g.temp(), g.temp()
+	top := newLabel()
+
+	g.move(imm(0), i)
+	g.place(top)
+	g.emit(intcode.OpAdd, imm(0), imm(0), g.indexed(a, i, 3))
+	g.emit(intcode.OpAdd, i, imm(1), i)
+	g.emit(intcode.OpLessThan, i, imm(a.size), more)
+	g.emit(intcode.OpJumpIfTrue, more, addrOf(top))
+}
+
+func (g *generator) stmts(stmts []stmt) {
+	for _, s := range stmts {
+		g.stmt(s)
+		g.temps = 0
+	}
+}
+
+func (g *generator) block(stmts []stmt) {
+	g.scopes = append(g.scopes, map[string]local{})
+	g.stmts(stmts)
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *generator) stmt(s stmt) {
+	switch s := s.(type) {
+	case *varDecl:
+		scope := g.scopes[len(g.scopes)-1]
+		if _, dup := scope[s.Name]; dup {
+			fail(s.Tok, "%s redeclared", s.Name)
+		}
+
+		if s.Size > 0 {
+			// The frame may hold leftovers of earlier calls (or loop iterations)
+			scope[s.Name] = local{g.nextLocal, s.Size}
+			g.nextLocal += s.Size
+			g.clear(g.array(s.Tok, s.Name))
+			return
+		}
+
+		value := imm(0)
+		if s.Init != nil {
+			value = g.expr(s.Init)
+		}
+
+		scope[s.Name] = local{offset: g.nextLocal}
+		g.move(value, rel(g.nextLocal))
+		g.nextLocal++
+
+	case *assignStmt:
+		if s.Index == nil {
+			dst := g.variable(s.Tok, s.Name)
+			g.move(g.expr(s.Value), dst)
+			return
+		}
+
+		arr := g.array(s.Tok, s.Name)
+		idx := g.expr(s.Index)
+		if hasCall(s.Value) {
+			idx = g.stable(idx)
+		}
+		value := g.expr(s.Value)
+
+		if idx.isConst() {
+			g.move(value, element(s.Tok, arr, idx.Value))
+			return
+		}
+
+		g.emit(intcode.OpAdd, value, imm(0), g.indexed(arr, idx, 3))
+
+	case *ifStmt:
+		elseL, end := newLabel(), newLabel()
+		g.emit(intcode.OpJumpIfFalse, g.expr(s.Cond), addrOf(elseL))
+		g.block(s.Then)
+		if s.Else != nil {
+			g.jump(end)
+		}
+		g.place(elseL)
+		g.block(s.Else)
+		g.place(end)
+
+	case *whileStmt:
+		top, end := newLabel(), newLabel()
+		g.place(top)
+		g.emit(intcode.OpJumpIfFalse, g.expr(s.Cond), addrOf(end))
+		g.block(s.Body)
+		g.jump(top)
+		g.place(end)
+
+	case *returnStmt:
+		value := imm(0)
+		if s.Value != nil {
+			value = g.expr(s.Value)
+		}
+		g.ret(value)
+
+	case *exprStmt:
+		g.expr(s.X)
+
+	default:
+		panic(fmt.Sprintf("compiler: unknown statement %T", s))
+	}
+}
+
+func (g *generator) ret(value operand) {
+	g.move(value, rel(1))
+	g.emit(intcode.OpJumpIfTrue, imm(1), rel(0))
+}
+
+func hasCall(e expr) bool {
+	switch e := e.(type) {
+	case *callExpr:
+		return true
+	case *indexExpr:
+		return hasCall(e.Index)
+	case *unaryExpr:
+		return hasCall(e.X)
+	case *binaryExpr:
+		return hasCall(e.L) || hasCall(e.R)
+	default:
+		return false
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// constValue evaluates expressions built only from numbers
+func constValue(e expr) (int, bool) {
+	switch e := e.(type) {
+	case *numberExpr:
+		return e.Value, true
+
+	case *unaryExpr:
+		x, ok := constValue(e.X)
+		if e.Op == "-" {
+			return -x, ok
+		}
+		return boolToInt(x == 0), ok
+
+	case *binaryExpr:
+		l, okL := constValue(e.L)
+		r, okR := constValue(e.R)
+		if !okL || !okR {
+			return 0, false
+		}
+
+		switch e.Op {
+		case "||":
+			return boolToInt(l != 0 || r != 0), true
+		case "&&":
+			return boolToInt(l != 0 && r != 0), true
+		case "==":
+			return boolToInt(l == r), true
+		case "!=":
+			return boolToInt(l != r), true
+		case "<":
+			return boolToInt(l < r), true
+		case "<=":
+			return boolToInt(l <= r), true
+		case ">":
+			return boolToInt(l > r), true
+		case ">=":
+			return boolToInt(l >= r), true
+		case "+":
+			return l + r, true
+		case "-":
+			return l - r, true
+		case "*":
+			return l * r, true
+		}
+	}
+	return 0, false
+}
+
+// expr generates code evaluating e, and returns the operand holding the result
+func (g *generator) expr(e expr) operand {
+	if v, ok := constValue(e); ok {
+		return imm(v)
+	}
+
+	switch e := e.(type) {
+	case *varExpr:
+		return g.variable(e.Tok, e.Name)
+
+	case *indexExpr:
+		arr := g.array(e.Tok, e.Name)
+		idx := g.expr(e.Index)
+		if idx.isConst() {
+			return element(e.Tok, arr, idx.Value)
+		}
+
+		t := g.temp()
+		g.emit(intcode.OpAdd, g.indexed(arr, idx, 1), imm(0), t)
+		return t
+
+	case *callExpr:
+		return g.call(e)
+
+	case *unaryExpr:
+		x := g.expr(e.X)
+		t := g.temp()
+		if e.Op == "-" {
+			g.emit(intcode.OpMul, x, imm(-1), t)
+		} else {
+			g.emit(intcode.OpEquals, x, imm(0), t)
+		}
+		return t
+
+	case *binaryExpr:
+		if e.Op == "&&" || e.Op == "||" {
+			return g.logical(e)
+		}
+
+		l := g.expr(e.L)
+		if hasCall(e.R) {
+			l = g.stable(l)
+		}
+		r := g.expr(e.R)
+		t := g.temp()
+
+		switch e.Op {
+		case "+":
+			g.emit(intcode.OpAdd, l, r, t)
+		case "-":
+			g.emit(intcode.OpMul, r, imm(-1), t)
+			g.emit(intcode.OpAdd, l, t, t)
+		case "*":
+			g.emit(intcode.OpMul, l, r, t)
+		case "==":
+			g.emit(intcode.OpEquals, l, r, t)
+		case "!=":
+			g.emit(intcode.OpEquals, l, r, t)
+			g.emit(intcode.OpEquals, t, imm(0), t)
+		case "<":
+			g.emit(intcode.OpLessThan, l, r, t)
+		case ">":
+			g.emit(intcode.OpLessThan, r, l, t)
+		case "<=":
+			g.emit(intcode.OpLessThan, r, l, t)
+			g.emit(intcode.OpEquals, t, imm(0), t)
+		case ">=":
+			g.emit(intcode.OpLessThan, l, r, t)
+			g.emit(intcode.OpEquals, t, imm(0), t)
+		default:
+			panic(fmt.Sprintf("compiler: unknown operator %s", e.Op))
+		}
+		return t
+
+	default:
+		panic(fmt.Sprintf("compiler: unknown expression %T", e))
+	}
+}
+
+// logical generates short-circuiting && and ||, which always result in 0 or 1
+func (g *generator) logical(e *binaryExpr) operand {
+	t := g.temp()
+	short, end := newLabel(), newLabel()
+
+	l := g.expr(e.L)
+	if e.Op == "&&" {
+		g.emit(intcode.OpJumpIfFalse, l, addrOf(short))
+	} else {
+		g.emit(intcode.OpJumpIfTrue, l, addrOf(short))
+	}
+
+	r := g.expr(e.R)
+	g.emit(intcode.OpEquals, r, imm(0), t)
+	g.emit(intcode.OpEquals, t, imm(0), t)
+	g.jump(end)
+
+	g.place(short)
+	g.move(imm(boolToInt(e.Op == "||")), t)
+	g.place(end)
+	return t
+}
+
+func (g *generator) call(c *callExpr) operand {
+	arity := func(n int) {
+		if len(c.Args) != n {
+			fail(c.Tok, "%s takes %d argument(s), got %d", c.Name, n, len(c.Args))
+		}
+	}
+
+	switch c.Name {
+	case "in":
+		arity(0)
+		t := g.temp()
+		g.emit(intcode.OpIn, t)
+		return t
+
+	case "out":
+		arity(1)
+		g.emit(intcode.OpOut, g.expr(c.Args[0]))
+		return imm(0)
+	}
+
+	var target operand
+	if f, ok := g.funcs[c.Name]; ok {
+		arity(len(f.decl.Params))
+		target = addrOf(f.l)
+	} else if _, ok := g.globals[c.Name]; ok {
+		fail(c.Tok, "%s is not a function", c.Name)
+	} else {
+		g.importSymbol(c.Name)
+		target = operand{Mode: intcode.ModeImmediate, Symbol: c.Name}
+	}
+
+	// Evaluate all arguments before filling the callee's frame,
+	// as the arguments may call other functions.
+	args := make([]operand, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = g.expr(arg)
+		if i < len(c.Args)-1 {
+			args[i] = g.stable(args[i])
+		}
+	}
+
+	ret := newLabel()
+	for i, arg := range args {
+		g.move(arg, operand{Mode: intcode.ModeRelative, Value: 2 + i, Frame: 1})
+	}
+	g.move(addrOf(ret), operand{Mode: intcode.ModeRelative, Frame: 1})
+	g.emit(intcode.OpAdjustRelativeBase, operand{Mode: intcode.ModeImmediate, Frame: 1})
+	g.emit(intcode.OpJumpIfTrue, imm(1), target)
+	g.place(ret)
+	g.emit(intcode.OpAdjustRelativeBase, operand{Mode: intcode.ModeImmediate, Frame: -1})
+
+	t := g.temp()
+	g.move(operand{Mode: intcode.ModeRelative, Value: 1, Frame: 1}, t)
+	return t
+}
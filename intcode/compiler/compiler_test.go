@@ -0,0 +1,203 @@
+package compiler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+)
+
+// run compiles and links the sources (the first one must define main),
+// and runs the result with the provided inputs
+func run(t *testing.T, inputs []int, sources ...string) []int {
+	t.Helper()
+
+	objects := make([]*intcode.Object, len(sources))
+	for idx, src := range sources {
+		o, err := Compile(string(rune('a'+idx)), strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("compile: %v", err)
+		}
+		objects[idx] = o
+	}
+
+	e, err := intcode.Link(objects...)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+
+	b := &strings.Builder{}
+	e.WriteTo(b)
+	i := intcode.NewSyncInterpreter(strings.NewReader(b.String()))
+	for _, x := range inputs {
+		i.Input.PushBack(x)
+	}
+
+	for i.ExecOne() == intcode.SyncExecutionStateReady {
+		if i.Steps > 1_000_000 {
+			t.Fatal("program did not halt")
+		}
+	}
+	if !i.Halted {
+		t.Fatal("program blocked on input")
+	}
+
+	var outputs []int
+	for i.Output.Len() > 0 {
+		outputs = append(outputs, i.Output.PopFront())
+	}
+	return outputs
+}
+
+var compilerCases = []struct {
+	name    string
+	src     string
+	inputs  []int
+	outputs []int
+}{
+	{
+		name: "echo",
+		src: `func main() {
+			var x = in();
+			while (x != 0) { out(x); x = in(); }
+		}`,
+		inputs:  []int{3, -7, 1, 0},
+		outputs: []int{3, -7, 1},
+	},
+	{
+		name: "arithmetic",
+		src: `func main() {
+			var a = in();
+			var b = in();
+			out(a + b); out(a - b); out(a * b); out(-a); out(2 * 3 + 4);
+			out(a < b); out(a <= b); out(a > b); out(a >= b); out(a == b); out(a != b);
+			out(!a); out(a && 0); out(a || 0); out(0 || b > 0);
+		}`,
+		inputs:  []int{5, 8},
+		outputs: []int{13, -3, 40, -5, 10, 1, 1, 0, 0, 0, 1, 0, 0, 1, 1},
+	},
+	{
+		name: "recursion",
+		src: `func fact(n) {
+			if (n <= 1) { return 1; }
+			return n * fact(n - 1);
+		}
+		func fib(n) {
+			if (n < 2) { return n; }
+			return fib(n - 1) + fib(n - 2);
+		}
+		func main() { out(fact(in())); out(fib(in())); }`,
+		inputs:  []int{10, 15},
+		outputs: []int{3628800, 610},
+	},
+	{
+		name: "arrays and globals",
+		src: `var n = 10;
+		var squares[10];
+		func main() {
+			var i = 0;
+			while (i < n) { squares[i] = i * i; i = i + 1; }
+			squares[0] = squares[9] + squares[in()];
+			var sum = 0;
+			i = 0;
+			while (i < n) { sum = sum + squares[i]; i = i + 1; }
+			out(squares[0]); out(sum);
+		}`,
+		inputs:  []int{2},
+		outputs: []int{85, 370},
+	},
+	{
+		name: "local arrays",
+		src: `func reverse(n) {
+			var buf[8];
+			var i = 0;
+			while (i < n) { buf[i] = in(); i = i + 1; }
+			while (i > 0) { i = i - 1; out(buf[i]); }
+		}
+		func nested(n) {
+			var a[2];
+			a[0] = n; a[1] = 10 * n;
+			if (n > 0) { nested(n - 1); }
+			out(a[0] + a[1]);
+		}
+		func main() {
+			reverse(in());
+			nested(2);
+			var k = 0;
+			while (k < 2) { var fresh[2]; out(fresh[0]); fresh[0] = 5; k = k + 1; }
+		}`,
+		inputs:  []int{3, 1, 2, 3},
+		outputs: []int{3, 2, 1, 0, 11, 22, 0, 0},
+	},
+	{
+		name: "else if and scopes",
+		src: `func sign(x) {
+			if (x < 0) { return -1; } else if (x == 0) { return 0; } else { return 1; }
+		}
+		func main() {
+			var x = 5;
+			if (1) { var x = 7; out(x); }
+			out(x);
+			out(sign(-9)); out(sign(0)); out(sign(9));
+		}`,
+		outputs: []int{7, 5, -1, 0, 1},
+	},
+	{
+		name: "argument order",
+		src: `var counter;
+		func next() { counter = counter + 1; return counter; }
+		func pair(a, b) { return 10 * a + b; }
+		func main() { out(pair(next(), next())); out(counter + next()); }`,
+		outputs: []int{12, 5},
+	},
+}
+
+func TestCompile(t *testing.T) {
+	for _, c := range compilerCases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := run(t, c.inputs, c.src); !reflect.DeepEqual(got, c.outputs) {
+				t.Errorf("got %v, expected %v", got, c.outputs)
+			}
+		})
+	}
+}
+
+func TestCompileSeparateObjects(t *testing.T) {
+	main := `func main() { out(triple(in())); }`
+	lib := `func triple(x) { return add(x, add(x, x)); }
+	func add(a, b) { return a + b; }`
+
+	if got := run(t, []int{14}, main, lib); !reflect.DeepEqual(got, []int{42}) {
+		t.Errorf("got %v, expected [42]", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"func main() {\n\tout(x);\n}", 2, "undefined variable x"},
+		{"var a[3];\nfunc main() { a[3] = 1; }", 2, "index 3 out of bounds"},
+		{"func f(x) {}\nfunc main() { f(); }", 2, "takes 1 argument"},
+		{"func main() {\n\tvar a[2];\n\ta = 1;\n}", 3, "a is an array"},
+		{"func main() {\n\tvar a[2];\n\tout(a[2]);\n}", 3, "index 2 out of bounds"},
+		{"func main() {\n\tvar x = 1 +;\n}", 2, "expected an expression"},
+		{"var in;", 1, "in is reserved"},
+		{"func main() { var x = 1 / 2; }", 1, "unexpected character"},
+	}
+
+	for _, c := range cases {
+		_, err := Compile("test", strings.NewReader(c.src))
+
+		var cErr *Error
+		if !errors.As(err, &cErr) {
+			t.Errorf("%q: expected a compilation error, got %v", c.src, err)
+		} else if cErr.Line != c.line || !strings.Contains(cErr.Msg, c.msg) {
+			t.Errorf("%q: got %v, expected %q at line %d", c.src, err, c.msg, c.line)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenKeyword
+	tokenPunct
+)
+
+type token struct {
+	Kind  tokenKind
+	Text  string
+	Value int // Only for tokenNumber
+	Line  int
+	Col   int
+}
+
+func (t token) String() string {
+	if t.Kind == tokenEOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+var keywords = map[string]bool{
+	"var":    true,
+	"func":   true,
+	"if":     true,
+	"else":   true,
+	"while":  true,
+	"return": true,
+}
+
+// Punctuation, longest first so that "==" is preferred over "="
+var puncts = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"(", ")", "{", "}", "[", "]", ",", ";", "=", "<", ">", "+", "-", "*", "!",
+}
+
+// Error is a compilation error with its position in the source
+type Error struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg) }
+
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+
+func lex(src string) (tokens []token, err error) {
+	line, col := 1, 1
+	advance := func(n int) {
+		for i := 0; i < n; i++ {
+			if src[i] == '\n' {
+				line, col = line+1, 1
+			} else {
+				col++
+			}
+		}
+		src = src[n:]
+	}
+
+	for len(src) > 0 {
+		c := src[0]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			advance(1)
+
+		case strings.HasPrefix(src, "//"):
+			end := strings.IndexByte(src, '\n')
+			if end < 0 {
+				end = len(src)
+			}
+			advance(end)
+
+		case isDigit(c):
+			end := 1
+			for end < len(src) && isDigit(src[end]) {
+				end++
+			}
+			value, err := strconv.Atoi(src[:end])
+			if err != nil {
+				return nil, &Error{line, col, fmt.Sprintf("invalid number %q", src[:end])}
+			}
+			tokens = append(tokens, token{tokenNumber, src[:end], value, line, col})
+			advance(end)
+
+		case isIdentStart(c):
+			end := 1
+			for end < len(src) && (isIdentStart(src[end]) || isDigit(src[end])) {
+				end++
+			}
+			kind := tokenIdent
+			if keywords[src[:end]] {
+				kind = tokenKeyword
+			}
+			tokens = append(tokens, token{kind, src[:end], 0, line, col})
+			advance(end)
+
+		default:
+			matched := false
+			for _, p := range puncts {
+				if strings.HasPrefix(src, p) {
+					tokens = append(tokens, token{tokenPunct, p, 0, line, col})
+					advance(len(p))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &Error{line, col, fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+
+	tokens = append(tokens, token{tokenEOF, "", 0, line, col})
+	return tokens, nil
+}
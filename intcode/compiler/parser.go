@@ -0,0 +1,379 @@
+package compiler
+
+import "fmt"
+
+type program struct {
+	Globals []*varDecl
+	Funcs   []*funcDecl
+}
+
+type varDecl struct {
+	Tok  token
+	Name string
+	Size int  // Amount of elements for arrays, 0 for scalars
+	Init expr // Optional
+}
+
+type funcDecl struct {
+	Tok    token
+	Name   string
+	Params []string
+	Body   []stmt
+}
+
+type stmt interface{ pos() token }
+
+type assignStmt struct {
+	Tok   token
+	Name  string
+	Index expr // nil for scalars
+	Value expr
+}
+
+type ifStmt struct {
+	Tok  token
+	Cond expr
+	Then []stmt
+	Else []stmt
+}
+
+type whileStmt struct {
+	Tok  token
+	Cond expr
+	Body []stmt
+}
+
+type returnStmt struct {
+	Tok   token
+	Value expr // Optional
+}
+
+type exprStmt struct {
+	X expr
+}
+
+type expr interface{ pos() token }
+
+type numberExpr struct {
+	Tok   token
+	Value int
+}
+
+type varExpr struct {
+	Tok  token
+	Name string
+}
+
+type indexExpr struct {
+	Tok   token
+	Name  string
+	Index expr
+}
+
+type callExpr struct {
+	Tok  token
+	Name string
+	Args []expr
+}
+
+type unaryExpr struct {
+	Tok token
+	Op  string
+	X   expr
+}
+
+type binaryExpr struct {
+	Tok  token
+	Op   string
+	L, R expr
+}
+
+func (s *varDecl) pos() token    { return s.Tok }
+func (s *assignStmt) pos() token { return s.Tok }
+func (s *ifStmt) pos() token     { return s.Tok }
+func (s *whileStmt) pos() token  { return s.Tok }
+func (s *returnStmt) pos() token { return s.Tok }
+func (s *exprStmt) pos() token   { return s.X.pos() }
+func (e *numberExpr) pos() token { return e.Tok }
+func (e *varExpr) pos() token    { return e.Tok }
+func (e *indexExpr) pos() token  { return e.Tok }
+func (e *callExpr) pos() token   { return e.Tok }
+func (e *unaryExpr) pos() token  { return e.Tok }
+func (e *binaryExpr) pos() token { return e.Tok }
+
+// Binary operators by precedence, loosest first
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*"},
+}
+
+// parser is a simple recursive-descent parser.
+// Errors are raised with panic(*Error) and recovered in parse.
+type parser struct {
+	tokens []token
+	idx    int
+}
+
+func parse(tokens []token) (p *program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*Error); ok {
+				err = e
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	ps := &parser{tokens: tokens}
+	p = &program{}
+	for ps.peek().Kind != tokenEOF {
+		switch {
+		case ps.is("var"):
+			p.Globals = append(p.Globals, ps.varDecl())
+		case ps.is("func"):
+			p.Funcs = append(p.Funcs, ps.funcDecl())
+		default:
+			ps.fail(ps.peek(), "expected \"var\" or \"func\", got %s", ps.peek())
+		}
+	}
+	return p, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.idx] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.idx]
+	if t.Kind != tokenEOF {
+		p.idx++
+	}
+	return t
+}
+
+func (p *parser) fail(at token, format string, args ...any) {
+	panic(&Error{at.Line, at.Col, fmt.Sprintf(format, args...)})
+}
+
+// is checks if the next token is the provided keyword or punctuation
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.Kind == tokenKeyword || t.Kind == tokenPunct) && t.Text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	if !p.is(text) {
+		p.fail(p.peek(), "expected %q, got %s", text, p.peek())
+	}
+	return p.next()
+}
+
+func (p *parser) expectIdent() token {
+	if p.peek().Kind != tokenIdent {
+		p.fail(p.peek(), "expected an identifier, got %s", p.peek())
+	}
+	return p.next()
+}
+
+func (p *parser) varDecl() *varDecl {
+	d := &varDecl{Tok: p.expect("var")}
+	d.Name = p.expectIdent().Text
+
+	if p.accept("[") {
+		size := p.next()
+		if size.Kind != tokenNumber || size.Value <= 0 {
+			p.fail(size, "expected a positive array size, got %s", size)
+		}
+		d.Size = size.Value
+		p.expect("]")
+	}
+
+	if p.accept("=") {
+		if d.Size > 0 {
+			p.fail(d.Tok, "arrays can't be initialized")
+		}
+		d.Init = p.expr()
+	}
+
+	p.expect(";")
+	return d
+}
+
+func (p *parser) funcDecl() *funcDecl {
+	f := &funcDecl{Tok: p.expect("func")}
+	f.Name = p.expectIdent().Text
+
+	p.expect("(")
+	for !p.is(")") {
+		if len(f.Params) > 0 {
+			p.expect(",")
+		}
+		f.Params = append(f.Params, p.expectIdent().Text)
+	}
+	p.expect(")")
+
+	f.Body = p.block()
+	return f
+}
+
+func (p *parser) block() (stmts []stmt) {
+	p.expect("{")
+	for !p.accept("}") {
+		if p.peek().Kind == tokenEOF {
+			p.fail(p.peek(), "unterminated block")
+		}
+		stmts = append(stmts, p.stmt())
+	}
+	return
+}
+
+func (p *parser) stmt() stmt {
+	switch {
+	case p.is("var"):
+		return p.varDecl()
+
+	case p.is("if"):
+		return p.ifStmt()
+
+	case p.is("while"):
+		s := &whileStmt{Tok: p.next()}
+		p.expect("(")
+		s.Cond = p.expr()
+		p.expect(")")
+		s.Body = p.block()
+		return s
+
+	case p.is("return"):
+		s := &returnStmt{Tok: p.next()}
+		if !p.is(";") {
+			s.Value = p.expr()
+		}
+		p.expect(";")
+		return s
+	}
+
+	// Assignments start with an identifier followed by "=" or "["
+	if p.peek().Kind == tokenIdent {
+		next := p.tokens[p.idx+1]
+		if next.Kind == tokenPunct && next.Text == "=" {
+			s := &assignStmt{Tok: p.next(), Name: p.tokens[p.idx-1].Text}
+			p.next()
+			s.Value = p.expr()
+			p.expect(";")
+			return s
+		} else if next.Kind == tokenPunct && next.Text == "[" {
+			// Might be an assignment, or an expression starting with an array element
+			start := p.idx
+			s := &assignStmt{Tok: p.next(), Name: p.tokens[p.idx-1].Text}
+			p.next()
+			s.Index = p.expr()
+			p.expect("]")
+			if p.accept("=") {
+				s.Value = p.expr()
+				p.expect(";")
+				return s
+			}
+			p.idx = start
+		}
+	}
+
+	s := &exprStmt{X: p.expr()}
+	p.expect(";")
+	return s
+}
+
+func (p *parser) ifStmt() stmt {
+	s := &ifStmt{Tok: p.expect("if")}
+	p.expect("(")
+	s.Cond = p.expr()
+	p.expect(")")
+	s.Then = p.block()
+
+	if p.accept("else") {
+		if p.is("if") {
+			s.Else = []stmt{p.ifStmt()}
+		} else {
+			s.Else = p.block()
+		}
+	}
+	return s
+}
+
+func (p *parser) expr() expr { return p.binary(0) }
+
+func (p *parser) binary(level int) expr {
+	if level == len(binaryPrecedence) {
+		return p.unary()
+	}
+
+	left := p.binary(level + 1)
+	for {
+		matched := false
+		for _, op := range binaryPrecedence[level] {
+			if p.is(op) {
+				tok := p.next()
+				left = &binaryExpr{tok, op, left, p.binary(level + 1)}
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return left
+		}
+	}
+}
+
+func (p *parser) unary() expr {
+	if p.is("-") || p.is("!") {
+		tok := p.next()
+		return &unaryExpr{tok, tok.Text, p.unary()}
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() expr {
+	t := p.next()
+	switch {
+	case t.Kind == tokenNumber:
+		return &numberExpr{t, t.Value}
+
+	case t.Kind == tokenPunct && t.Text == "(":
+		e := p.expr()
+		p.expect(")")
+		return e
+
+	case t.Kind == tokenIdent && p.accept("("):
+		c := &callExpr{Tok: t, Name: t.Text}
+		for !p.is(")") {
+			if len(c.Args) > 0 {
+				p.expect(",")
+			}
+			c.Args = append(c.Args, p.expr())
+		}
+		p.expect(")")
+		return c
+
+	case t.Kind == tokenIdent && p.accept("["):
+		e := &indexExpr{Tok: t, Name: t.Text, Index: p.expr()}
+		p.expect("]")
+		return e
+
+	case t.Kind == tokenIdent:
+		return &varExpr{t, t.Text}
+
+	default:
+		p.fail(t, "expected an expression, got %s", t)
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package intcode
+
+import (
+	"github.com/MKuranowski/AdventOfCode2019/util/set"
+)
+
+// Debugger executes a SyncInterpreter while remembering every executed instruction,
+// which allows the execution to be reversed.
+//
+// Undoing an OUT instruction removes the value from the back of the output queue,
+// so values already taken from the output can't be taken back - StepBack refuses to undo them.
+type Debugger struct {
+	M           *SyncInterpreter
+	History     []TraceEntry
+	Breakpoints set.Set[int]
+
+	outputLens []int // Length of the output queue right after every OUT in History
+}
+
+func NewDebugger(m *SyncInterpreter) *Debugger {
+	return &Debugger{M: m, Breakpoints: make(set.Set[int])}
+}
+
+func (d *Debugger) Trace(e TraceEntry) {
+	d.History = append(d.History, e)
+	if e.IsOutput() {
+		d.outputLens = append(d.outputLens, d.M.Output.Len())
+	}
+}
+
+// Step executes a single instruction
+func (d *Debugger) Step() SyncExecutionState { return d.M.ExecOneTraced(d) }
+
+// StepBack undoes the last executed instruction.
+// Returns false if there's nothing to undo, or if the last instruction was an OUT
+// whose value has already been taken from the output queue.
+func (d *Debugger) StepBack() bool {
+	if len(d.History) == 0 {
+		return false
+	}
+
+	e := d.History[len(d.History)-1]
+	if e.IsOutput() {
+		// The output is consumed from the front, so the value is still queued
+		// if the queue hasn't been emptied since, and nothing else was added to it
+		l := d.M.Output.Len()
+		if l == 0 || l > d.outputLens[len(d.outputLens)-1] || d.M.Output.PeekBack() != e.Output {
+			return false
+		}
+		d.M.Output.PopBack()
+		d.outputLens = d.outputLens[:len(d.outputLens)-1]
+	}
+	d.History = d.History[:len(d.History)-1]
+
+	d.M.IP = e.IP
+	d.M.RelativeBase = e.RelativeBase
+	d.M.Steps = e.Step
+	d.M.Halted = false
+
+	if e.Write >= 0 && e.Write < e.MemoryLen {
+		d.M.Memory[e.Write] = e.OldValue
+	}
+	d.M.Memory = d.M.Memory[:e.MemoryLen]
+
+	if e.IsInput() {
+		d.M.Input.PushFront(e.NewValue)
+	}
+
+	return true
+}
+
+// Continue executes instructions until the program halts, blocks on input,
+// or a breakpoint is reached. At least one instruction is always executed.
+// SyncExecutionStateReady is returned if the execution stopped on a breakpoint.
+func (d *Debugger) Continue() SyncExecutionState {
+	state := d.Step()
+	for state == SyncExecutionStateReady && !d.Breakpoints.Has(d.M.IP) {
+		state = d.Step()
+	}
+	return state
+}
+
+// ReverseContinue undoes instructions until a breakpoint is reached,
+// or until nothing more can be undone (see StepBack). At least one instruction is always undone.
+// Returns true if the execution stopped on a breakpoint.
+func (d *Debugger) ReverseContinue() bool {
+	for d.StepBack() {
+		if d.Breakpoints.Has(d.M.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// StepBackTo undoes instructions until Steps is equal to step.
+// Returns false if the history doesn't go back far enough (see StepBack).
+func (d *Debugger) StepBackTo(step int) bool {
+	for d.M.Steps > step {
+		if !d.StepBack() {
+			return false
+		}
+	}
+	return d.M.Steps == step
+}
+
+// LastWriteTo returns the most recently executed instruction which wrote to addr.
+func (d *Debugger) LastWriteTo(addr int) (e TraceEntry, ok bool) {
+	for idx := len(d.History) - 1; idx >= 0; idx-- {
+		if d.History[idx].Write == addr {
+			return d.History[idx], true
+		}
+	}
+	return e, false
+}
@@ -0,0 +1,89 @@
+package intcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// doubleProgram reads a value, and outputs it doubled
+const doubleProgram = "3,9,1002,9,2,10,4,10,99,0,0"
+
+func newDoubleDebugger(input int) *Debugger {
+	m := NewSyncInterpreter(strings.NewReader(doubleProgram))
+	m.Input.PushBack(input)
+	return NewDebugger(m)
+}
+
+func TestDebuggerStepBack(t *testing.T) {
+	d := newDoubleDebugger(21)
+	initial := append([]int(nil), d.M.Memory...)
+
+	if state := d.Continue(); state != SyncExecutionStateHalted {
+		t.Fatalf("got state %v, expected halted", state)
+	} else if d.M.Output.Len() != 1 || d.M.Output.PeekFront() != 42 {
+		t.Fatalf("expected a single output of 42")
+	}
+
+	if !d.StepBackTo(0) {
+		t.Fatal("StepBackTo(0) failed")
+	}
+	if d.M.IP != 0 || d.M.Halted || !reflect.DeepEqual(d.M.Memory, initial) {
+		t.Errorf("state not restored: ip %d, halted %v, memory %v", d.M.IP, d.M.Halted, d.M.Memory)
+	}
+	if d.M.Output.Len() != 0 {
+		t.Errorf("OUT not undone, %d values queued", d.M.Output.Len())
+	}
+	if d.M.Input.Len() != 1 || d.M.Input.PeekFront() != 21 {
+		t.Errorf("IN not undone")
+	}
+
+	// The replay should behave exactly the same
+	d.Continue()
+	if d.M.Output.Len() != 1 || d.M.Output.PeekFront() != 42 {
+		t.Errorf("expected a single output of 42 after re-execution")
+	}
+}
+
+func TestDebuggerReverseContinue(t *testing.T) {
+	d := newDoubleDebugger(21)
+	d.Continue()
+
+	// Stop before the OUT instruction
+	d.Breakpoints.Add(6)
+	if !d.ReverseContinue() {
+		t.Fatal("breakpoint not reached")
+	}
+	if d.M.IP != 6 || d.M.Output.Len() != 0 || d.M.Memory[10] != 42 {
+		t.Errorf("unexpected state: ip %d, output length %d, result %d", d.M.IP, d.M.Output.Len(), d.M.Memory[10])
+	}
+
+	// No more breakpoints up to the start
+	if d.ReverseContinue() {
+		t.Error("unexpected breakpoint")
+	}
+	if d.M.Steps != 0 || d.M.Input.Len() != 1 {
+		t.Errorf("expected to be back at the start, got %d steps", d.M.Steps)
+	}
+}
+
+func TestDebuggerStepBackOverConsumedOutput(t *testing.T) {
+	d := newDoubleDebugger(21)
+	d.Continue()
+	d.M.Output.PopFront()
+
+	if d.StepBackTo(0) {
+		t.Fatal("stepped back over a consumed output")
+	}
+
+	// Everything after the OUT is undone, but not the OUT itself
+	if d.M.IP != 8 || d.M.Halted || d.M.Memory[10] != 42 {
+		t.Errorf("unexpected state: ip %d, halted %v, result %d", d.M.IP, d.M.Halted, d.M.Memory[10])
+	}
+	if d.StepBack() || d.ReverseContinue() {
+		t.Error("stepped back over a consumed output")
+	}
+	if d.M.Output.Len() != 0 {
+		t.Errorf("output queue modified, %d values queued", d.M.Output.Len())
+	}
+}
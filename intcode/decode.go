@@ -0,0 +1,184 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownOpcode      = errors.New("unknown opcode")
+	ErrUnknownMode        = errors.New("unknown parameter mode")
+	ErrTruncatedOperation = errors.New("operation extends past the end of memory")
+)
+
+type Opcode int
+
+const (
+	OpAdd                Opcode = 1
+	OpMul                Opcode = 2
+	OpIn                 Opcode = 3
+	OpOut                Opcode = 4
+	OpJumpIfTrue         Opcode = 5
+	OpJumpIfFalse        Opcode = 6
+	OpLessThan           Opcode = 7
+	OpEquals             Opcode = 8
+	OpAdjustRelativeBase Opcode = 9
+	OpHalt               Opcode = 99
+)
+
+// Params returns the amount of parameters taken by the opcode,
+// or -1 if the opcode is unknown.
+func (o Opcode) Params() int {
+	switch o {
+	case OpAdd, OpMul, OpLessThan, OpEquals:
+		return 3
+	case OpJumpIfTrue, OpJumpIfFalse:
+		return 2
+	case OpIn, OpOut, OpAdjustRelativeBase:
+		return 1
+	case OpHalt:
+		return 0
+	default:
+		return -1
+	}
+}
+
+func (o Opcode) String() string {
+	switch o {
+	case OpAdd:
+		return "ADD"
+	case OpMul:
+		return "MUL"
+	case OpIn:
+		return "IN"
+	case OpOut:
+		return "OUT"
+	case OpJumpIfTrue:
+		return "JNZ"
+	case OpJumpIfFalse:
+		return "JZ"
+	case OpLessThan:
+		return "LT"
+	case OpEquals:
+		return "EQ"
+	case OpAdjustRelativeBase:
+		return "ARB"
+	case OpHalt:
+		return "HALT"
+	default:
+		return fmt.Sprintf("OP%d", int(o))
+	}
+}
+
+type Mode uint8
+
+const (
+	ModePosition Mode = iota
+	ModeImmediate
+	ModeRelative
+)
+
+// Instruction is a decoded intcode operation with its raw parameters.
+type Instruction struct {
+	Op     Opcode
+	Modes  [3]Mode
+	Params [3]int
+}
+
+// Decode decodes the operation stored at memory[ip].
+func Decode(memory []int, ip int) (ins Instruction, err error) {
+	if ip < 0 || ip >= len(memory) {
+		return ins, fmt.Errorf("decode at %d: %w", ip, ErrTruncatedOperation)
+	}
+
+	modes := memory[ip] / 100
+	ins.Op = Opcode(memory[ip] % 100)
+	params := ins.Op.Params()
+	if params < 0 {
+		return ins, fmt.Errorf("decode at %d: %w: %d", ip, ErrUnknownOpcode, memory[ip])
+	} else if ip+params >= len(memory) {
+		return ins, fmt.Errorf("decode at %d: %w", ip, ErrTruncatedOperation)
+	}
+
+	for arg := 0; arg < params; arg++ {
+		mode := Mode((modes / powerOfTen(arg)) % 10)
+		if mode > ModeRelative {
+			return ins, fmt.Errorf("decode at %d: %w: %d", ip, ErrUnknownMode, memory[ip])
+		}
+		ins.Modes[arg] = mode
+		ins.Params[arg] = memory[ip+arg+1]
+	}
+
+	return ins, nil
+}
+
+// Size returns the amount of memory cells taken by the instruction.
+func (ins Instruction) Size() int { return 1 + ins.Op.Params() }
+
+// Address returns the memory address referenced by the zero-based argument arg.
+// ok is false for immediate arguments.
+func (ins Instruction) Address(arg, relativeBase int) (addr int, ok bool) {
+	switch ins.Modes[arg] {
+	case ModePosition:
+		return ins.Params[arg], true
+	case ModeRelative:
+		return relativeBase + ins.Params[arg], true
+	default:
+		return 0, false
+	}
+}
+
+// Get returns the value of the zero-based argument arg. Cells past the end of memory read as zero.
+func (ins Instruction) Get(arg int, memory []int, relativeBase int) int {
+	addr, ok := ins.Address(arg, relativeBase)
+	if !ok {
+		return ins.Params[arg]
+	} else if addr < 0 || addr >= len(memory) {
+		return 0
+	}
+	return memory[addr]
+}
+
+// Writes returns the zero-based index of the argument which the instruction writes to.
+func (ins Instruction) Writes() (arg int, ok bool) {
+	switch ins.Op {
+	case OpAdd, OpMul, OpLessThan, OpEquals:
+		return 2, true
+	case OpIn:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// WriteAddress returns the memory address written by the instruction.
+func (ins Instruction) WriteAddress(relativeBase int) (addr int, ok bool) {
+	arg, ok := ins.Writes()
+	if !ok {
+		return 0, false
+	}
+	return ins.Address(arg, relativeBase)
+}
+
+func (ins Instruction) String() string {
+	b := strings.Builder{}
+	b.WriteString(ins.Op.String())
+	for arg := 0; arg < ins.Op.Params(); arg++ {
+		if arg == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(", ")
+		}
+
+		switch ins.Modes[arg] {
+		case ModePosition:
+			fmt.Fprintf(&b, "[%d]", ins.Params[arg])
+		case ModeImmediate:
+			fmt.Fprintf(&b, "%d", ins.Params[arg])
+		case ModeRelative:
+			fmt.Fprintf(&b, "[rb%+d]", ins.Params[arg])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package intcode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	fuzzMaxSteps   = 10_000
+	fuzzMaxAddress = 1 << 16
+)
+
+type fuzzOutcome uint8
+
+const (
+	fuzzOutcomeHalted fuzzOutcome = iota
+	fuzzOutcomeBlocked
+	fuzzOutcomeOutOfSteps
+	fuzzOutcomeOutOfMemory
+	fuzzOutcomePanic
+)
+
+type fuzzResult struct {
+	Outcome fuzzOutcome
+	Outputs []int
+	Memory  []int
+}
+
+// withinLimits checks that the instruction at ip doesn't access huge addresses,
+// which would make the interpreters try to allocate gigabytes of memory.
+func withinLimits(memory []int, ip, relativeBase int) bool {
+	if ip < 0 || ip >= len(memory) {
+		return true
+	}
+
+	// Instructions truncated by the end of memory may still be executed -
+	// their parameters can make the memory grow. Decode them as if the memory was padded with zeros.
+	padded := make([]int, 4)
+	copy(padded, memory[ip:])
+
+	ins, err := Decode(padded, 0)
+	if err != nil {
+		// Let the interpreter handle (panic on) invalid instructions
+		return true
+	}
+
+	for arg := 0; arg < ins.Op.Params(); arg++ {
+		if addr, ok := ins.Address(arg, relativeBase); ok && addr > fuzzMaxAddress {
+			return false
+		}
+	}
+	return true
+}
+
+func fuzzChannel(program string, inputs []int) (r fuzzResult) {
+	in := make(chan int, len(inputs))
+	for _, x := range inputs {
+		in <- x
+	}
+	close(in)
+
+	i := NewInterpreterWithIO(strings.NewReader(program), in, make(chan int, fuzzMaxSteps))
+
+	defer func() {
+		if p := recover(); p != nil {
+			if err, ok := p.(error); ok && errors.Is(err, ErrInputOverClosed) {
+				r.Outcome = fuzzOutcomeBlocked
+			} else {
+				r.Outcome = fuzzOutcomePanic
+			}
+		}
+
+		close(i.Output)
+		for x := range i.Output {
+			r.Outputs = append(r.Outputs, x)
+		}
+		r.Memory = i.Memory
+	}()
+
+	r.Outcome = fuzzOutcomeOutOfSteps
+	for step := 0; step < fuzzMaxSteps; step++ {
+		if !withinLimits(i.Memory, i.IP, i.RelativeBase) {
+			r.Outcome = fuzzOutcomeOutOfMemory
+			break
+		} else if !i.ExecOne() {
+			r.Outcome = fuzzOutcomeHalted
+			break
+		}
+	}
+	return
+}
+
+func fuzzSync(program string, inputs []int) (r fuzzResult) {
+	i := NewSyncInterpreter(strings.NewReader(program))
+	for _, x := range inputs {
+		i.Input.PushBack(x)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			r.Outcome = fuzzOutcomePanic
+		}
+
+		for i.Output.Len() > 0 {
+			r.Outputs = append(r.Outputs, i.Output.PopFront())
+		}
+		r.Memory = i.Memory
+	}()
+
+	r.Outcome = fuzzOutcomeOutOfSteps
+	for step := 0; step < fuzzMaxSteps; step++ {
+		if !withinLimits(i.Memory, i.IP, i.RelativeBase) {
+			r.Outcome = fuzzOutcomeOutOfMemory
+			break
+		}
+
+		switch i.ExecOne() {
+		case SyncExecutionStateHalted:
+			r.Outcome = fuzzOutcomeHalted
+			return
+		case SyncExecutionStateBlockedOnInput:
+			r.Outcome = fuzzOutcomeBlocked
+			return
+		}
+	}
+	return
+}
+
+func FuzzLoader(f *testing.F) {
+	for _, c := range conformanceCases {
+		f.Add([]byte(c.program))
+	}
+	f.Add([]byte(""))
+	f.Add([]byte("1,,2\n"))
+	f.Add([]byte("-,+,99999999999999999999999,\n\n"))
+
+	f.Fuzz(func(t *testing.T, program []byte) {
+		i := NewInterpreter(bytes.NewReader(program))
+		s := NewSyncInterpreter(bytes.NewReader(program))
+
+		if len(i.Memory) == 0 {
+			t.Error("Interpreter has no memory")
+		}
+		if !reflect.DeepEqual(i.Memory, s.Memory) {
+			t.Errorf("loaders disagree: %v vs %v", i.Memory, s.Memory)
+		}
+	})
+}
+
+func FuzzInterpreters(f *testing.F) {
+	for _, c := range conformanceCases {
+		in0, in1 := 0, 0
+		if len(c.inputs) > 0 {
+			in0 = c.inputs[0]
+		}
+		if len(c.inputs) > 1 {
+			in1 = c.inputs[1]
+		}
+		f.Add(c.program, in0, in1)
+	}
+	f.Add("3,0,3,1,1,0,1,2,4,2,99", 20, 22)
+	f.Add("109,-1,4,1,99", 0, 0)
+
+	f.Fuzz(func(t *testing.T, program string, in0, in1 int) {
+		inputs := []int{in0, in1}
+		chanResult := fuzzChannel(program, inputs)
+		syncResult := fuzzSync(program, inputs)
+
+		if chanResult.Outcome != syncResult.Outcome {
+			t.Fatalf("outcomes differ: Interpreter %d, SyncInterpreter %d",
+				chanResult.Outcome, syncResult.Outcome)
+		}
+		if !reflect.DeepEqual(chanResult.Outputs, syncResult.Outputs) {
+			t.Errorf("outputs differ: Interpreter %v, SyncInterpreter %v",
+				chanResult.Outputs, syncResult.Outputs)
+		}
+		if !reflect.DeepEqual(chanResult.Memory, syncResult.Memory) {
+			t.Errorf("memory differs: Interpreter %v, SyncInterpreter %v",
+				chanResult.Memory, syncResult.Memory)
+		}
+	})
+}
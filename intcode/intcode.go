@@ -67,6 +67,7 @@ type Interpreter struct {
 	IP           int
 	Halted       chan struct{}
 	RelativeBase int
+	Steps        int // Amount of executed instructions
 
 	Input  chan int
 	Output chan int
@@ -214,6 +215,7 @@ func (i *Interpreter) ExecOne() (more bool) {
 	}
 
 	i.IP += opSize
+	i.Steps++
 	return !i.IsHalted()
 }
 
@@ -230,6 +232,7 @@ func (i *Interpreter) Clone() (new *Interpreter) {
 	new = &Interpreter{
 		Memory: append([]int(nil), i.Memory...),
 		IP:     i.IP,
+		Steps:  i.Steps,
 		Halted: make(chan struct{}),
 	}
 	if i.IsHalted() {
@@ -285,6 +288,7 @@ type SyncInterpreter struct {
 	Memory       []int
 	IP           int
 	RelativeBase int
+	Steps        int // Amount of executed instructions
 
 	Halted        bool
 	Input, Output deque.Deque[int]
@@ -428,6 +432,7 @@ func (i *SyncInterpreter) ExecOne() SyncExecutionState {
 	}
 
 	i.IP += opSize
+	i.Steps++
 	if i.Halted {
 		return SyncExecutionStateHalted
 	}
@@ -447,6 +452,7 @@ func (i *SyncInterpreter) Clone() (new *SyncInterpreter) {
 		Memory:       append([]int(nil), i.Memory...),
 		IP:           i.IP,
 		RelativeBase: i.RelativeBase,
+		Steps:        i.Steps,
 		Halted:       i.Halted,
 		Input:        deque.NewDeque[int](),
 		Output:       deque.NewDeque[int](),
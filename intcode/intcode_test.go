@@ -0,0 +1,166 @@
+package intcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runChannel runs a program on an Interpreter, returning its outputs and final memory
+func runChannel(program string, inputs []int) (outputs []int, memory []int) {
+	in := make(chan int, len(inputs))
+	for _, x := range inputs {
+		in <- x
+	}
+	close(in)
+
+	i := NewInterpreterWithIO(strings.NewReader(program), in, make(chan int))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := range i.Output {
+			outputs = append(outputs, x)
+		}
+	}()
+
+	i.ExecAll()
+	<-done
+	return outputs, i.Memory
+}
+
+// runSync runs a program on a SyncInterpreter, returning its outputs and final memory
+func runSync(program string, inputs []int) (outputs []int, memory []int, state SyncExecutionState) {
+	i := NewSyncInterpreter(strings.NewReader(program))
+	for _, x := range inputs {
+		i.Input.PushBack(x)
+	}
+
+	state = i.ExecAll()
+	for i.Output.Len() > 0 {
+		outputs = append(outputs, i.Output.PopFront())
+	}
+	return outputs, i.Memory, state
+}
+
+var conformanceCases = []struct {
+	name       string
+	program    string
+	inputs     []int
+	wantOutput []int
+	wantMemory []int // nil if the memory isn't checked
+}{
+	// Day 2 - ADD, MUL and HALT in position mode
+	{"day2 add", "1,0,0,0,99", nil, nil, []int{2, 0, 0, 0, 99}},
+	{"day2 mul", "2,3,0,3,99", nil, nil, []int{2, 3, 0, 6, 99}},
+	{"day2 mul past halt", "2,4,4,5,99,0", nil, nil, []int{2, 4, 4, 5, 99, 9801}},
+	{"day2 self modifying", "1,1,1,4,99,5,6,0,99", nil, nil, []int{30, 1, 1, 4, 2, 5, 6, 0, 99}},
+	{"day2 example", "1,9,10,3,2,3,11,0,99,30,40,50", nil, nil,
+		[]int{3500, 9, 10, 70, 2, 3, 11, 0, 99, 30, 40, 50}},
+
+	// Day 5 - IN, OUT, immediate mode, comparisons and jumps
+	{"day5 echo", "3,0,4,0,99", []int{42}, []int{42}, []int{42, 0, 4, 0, 99}},
+	{"day5 immediate mul", "1002,4,3,4,33", nil, nil, []int{1002, 4, 3, 4, 99}},
+	{"day5 negative immediate", "1101,100,-1,4,0", nil, nil, []int{1101, 100, -1, 4, 99}},
+	{"day5 eq position true", "3,9,8,9,10,9,4,9,99,-1,8", []int{8}, []int{1}, nil},
+	{"day5 eq position false", "3,9,8,9,10,9,4,9,99,-1,8", []int{7}, []int{0}, nil},
+	{"day5 lt position true", "3,9,7,9,10,9,4,9,99,-1,8", []int{7}, []int{1}, nil},
+	{"day5 lt position false", "3,9,7,9,10,9,4,9,99,-1,8", []int{8}, []int{0}, nil},
+	{"day5 eq immediate true", "3,3,1108,-1,8,3,4,3,99", []int{8}, []int{1}, nil},
+	{"day5 eq immediate false", "3,3,1108,-1,8,3,4,3,99", []int{9}, []int{0}, nil},
+	{"day5 lt immediate true", "3,3,1107,-1,8,3,4,3,99", []int{-3}, []int{1}, nil},
+	{"day5 lt immediate false", "3,3,1107,-1,8,3,4,3,99", []int{8}, []int{0}, nil},
+	{"day5 jz position zero", "3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9", []int{0}, []int{0}, nil},
+	{"day5 jz position nonzero", "3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9", []int{5}, []int{1}, nil},
+	{"day5 jnz immediate zero", "3,3,1105,-1,9,1101,0,0,12,4,12,99,1", []int{0}, []int{0}, nil},
+	{"day5 jnz immediate nonzero", "3,3,1105,-1,9,1101,0,0,12,4,12,99,1", []int{5}, []int{1}, nil},
+	{"day5 compare below", day5Compare, []int{7}, []int{999}, nil},
+	{"day5 compare equal", day5Compare, []int{8}, []int{1000}, nil},
+	{"day5 compare above", day5Compare, []int{9}, []int{1001}, nil},
+
+	// Day 9 - relative mode, large numbers and memory past the program
+	{"day9 quine", day9Quine, nil,
+		[]int{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16, 101, 1006, 101, 0, 99}, nil},
+	{"day9 large multiplication", "1102,34915192,34915192,7,4,7,99,0", nil, []int{1219070632396864}, nil},
+	{"day9 large output", "104,1125899906842624,99", nil, []int{1125899906842624}, nil},
+	{"relative input and output", "109,10,203,0,204,0,99", []int{-7}, []int{-7},
+		[]int{109, 10, 203, 0, 204, 0, 99, 0, 0, 0, -7}},
+}
+
+const day5Compare = "3,21,1008,21,8,20,1005,20,22,107,8,21,20,1006,20,31,1106,0,36,98,0,0,1002,21,125," +
+	"20,4,20,1105,1,46,104,999,1105,1,46,1101,1000,1,20,4,20,1105,1,46,98,99"
+
+const day9Quine = "109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99"
+
+func TestConformance(t *testing.T) {
+	for _, c := range conformanceCases {
+		t.Run(c.name, func(t *testing.T) {
+			chanOutput, chanMemory := runChannel(c.program, c.inputs)
+			syncOutput, syncMemory, state := runSync(c.program, c.inputs)
+
+			if state != SyncExecutionStateHalted {
+				t.Errorf("SyncInterpreter has not halted, state: %d", state)
+			}
+
+			if !reflect.DeepEqual(chanOutput, c.wantOutput) {
+				t.Errorf("Interpreter output: got %v, want %v", chanOutput, c.wantOutput)
+			}
+			if !reflect.DeepEqual(syncOutput, c.wantOutput) {
+				t.Errorf("SyncInterpreter output: got %v, want %v", syncOutput, c.wantOutput)
+			}
+
+			if c.wantMemory != nil {
+				if !reflect.DeepEqual(chanMemory, c.wantMemory) {
+					t.Errorf("Interpreter memory: got %v, want %v", chanMemory, c.wantMemory)
+				}
+				if !reflect.DeepEqual(syncMemory, c.wantMemory) {
+					t.Errorf("SyncInterpreter memory: got %v, want %v", syncMemory, c.wantMemory)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncInterpreterBlocksOnInput(t *testing.T) {
+	i := NewSyncInterpreter(strings.NewReader("3,0,4,0,3,0,4,0,99"))
+	i.Input.PushBack(1)
+
+	if state := i.ExecAll(); state != SyncExecutionStateBlockedOnInput {
+		t.Fatalf("expected to block on input, got state %d", state)
+	}
+	if i.IP != 4 {
+		t.Errorf("expected to block at IP 4, got %d", i.IP)
+	}
+
+	i.Input.PushBack(2)
+	if state := i.ExecAll(); state != SyncExecutionStateHalted {
+		t.Fatalf("expected to halt, got state %d", state)
+	}
+
+	got := []int{i.Output.PopFront(), i.Output.PopFront()}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got outputs %v, want [1 2]", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	ins, err := Decode([]int{21101, 2, -3, 4}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ins.String(); got != "ADD 2, -3, [rb+4]" {
+		t.Errorf("got %q, want %q", got, "ADD 2, -3, [rb+4]")
+	}
+
+	if _, err := Decode([]int{42}, 0); err == nil {
+		t.Error("expected an error for an unknown opcode")
+	}
+
+	if _, err := Decode([]int{301, 0, 0, 0}, 0); err == nil {
+		t.Error("expected an error for an unknown parameter mode")
+	}
+
+	if _, err := Decode([]int{1, 0, 0}, 0); err == nil {
+		t.Error("expected an error for a truncated instruction")
+	}
+}
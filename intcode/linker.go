@@ -0,0 +1,254 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+var (
+	ErrDuplicateSymbol = errors.New("duplicate symbol")
+	ErrUndefinedSymbol = errors.New("undefined symbol")
+	ErrInvalidObject   = errors.New("invalid object")
+)
+
+// EndSymbol is automatically defined by Link as the first address past the linked program.
+// It may be imported, but not exported.
+const EndSymbol = "__end"
+
+// Relocation marks a word of an Object's code which holds an address.
+//
+// If Symbol is empty, the word holds an address relative to the start of the object,
+// and the object's base address is added to it. Otherwise the address of Symbol is added to it.
+type Relocation struct {
+	Offset int
+	Symbol string
+}
+
+// Object is a relocatable piece of intcode, which can be combined with other objects by Link.
+//
+// The text representation consists of directives and code:
+//
+//	# comments start with a hash
+//	.module print
+//	.export print_num 0
+//	.import putc
+//	.reloc 5
+//	.reloc 12 putc
+//	1105,1,0,
+//	99
+//
+// Lines which don't start with a dot contain comma-separated code.
+type Object struct {
+	Name        string
+	Code        []int
+	Exports     map[string]int // Symbol name to its offset within Code
+	Imports     []string
+	Relocations []Relocation
+}
+
+func NewObject(name string) *Object {
+	return &Object{Name: name, Exports: make(map[string]int)}
+}
+
+// Validate ensures that all exports and relocations point into the code,
+// and that relocations only use declared symbols.
+func (o *Object) Validate() error {
+	declared := make(map[string]bool)
+	for _, sym := range o.Imports {
+		declared[sym] = true
+	}
+
+	for sym, offset := range o.Exports {
+		if offset < 0 || offset > len(o.Code) {
+			return fmt.Errorf("%w: %s: export %s points outside of code", ErrInvalidObject, o.Name, sym)
+		} else if declared[sym] {
+			return fmt.Errorf("%w: %s: %s is both imported and exported", ErrInvalidObject, o.Name, sym)
+		}
+		declared[sym] = true
+	}
+
+	for _, r := range o.Relocations {
+		if r.Offset < 0 || r.Offset >= len(o.Code) {
+			return fmt.Errorf("%w: %s: relocation at %d points outside of code", ErrInvalidObject, o.Name, r.Offset)
+		} else if r.Symbol != "" && !declared[r.Symbol] {
+			return fmt.Errorf("%w: %s: relocation at %d uses undeclared symbol %s",
+				ErrInvalidObject, o.Name, r.Offset, r.Symbol)
+		}
+	}
+
+	return nil
+}
+
+func ReadObject(r io.Reader) (*Object, error) {
+	o := NewObject("")
+
+	for lineNo, line := range input.ReadLines(r) {
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		} else if !strings.HasPrefix(fields[0], ".") {
+			for _, value := range strings.Split(strings.Join(fields, ""), ",") {
+				if value == "" {
+					continue
+				}
+
+				x, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("object line %d: %w", lineNo+1, err)
+				}
+				o.Code = append(o.Code, x)
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case fields[0] == ".module" && len(fields) == 2:
+			o.Name = fields[1]
+		case fields[0] == ".import" && len(fields) == 2:
+			o.Imports = append(o.Imports, fields[1])
+		case fields[0] == ".export" && len(fields) == 3:
+			o.Exports[fields[1]], err = strconv.Atoi(fields[2])
+		case fields[0] == ".reloc" && (len(fields) == 2 || len(fields) == 3):
+			r := Relocation{}
+			r.Offset, err = strconv.Atoi(fields[1])
+			if len(fields) == 3 {
+				r.Symbol = fields[2]
+			}
+			o.Relocations = append(o.Relocations, r)
+		default:
+			err = fmt.Errorf("%w: unknown directive: %q", ErrInvalidObject, line)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("object line %d: %w", lineNo+1, err)
+		}
+	}
+
+	return o, o.Validate()
+}
+
+func (o *Object) WriteTo(w io.Writer) (n int64, err error) {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, ".module %s\n", o.Name)
+
+	for _, sym := range o.Imports {
+		fmt.Fprintf(b, ".import %s\n", sym)
+	}
+
+	exports := make([]string, 0, len(o.Exports))
+	for sym := range o.Exports {
+		exports = append(exports, sym)
+	}
+	sort.Strings(exports)
+	for _, sym := range exports {
+		fmt.Fprintf(b, ".export %s %d\n", sym, o.Exports[sym])
+	}
+
+	for _, r := range o.Relocations {
+		if r.Symbol == "" {
+			fmt.Fprintf(b, ".reloc %d\n", r.Offset)
+		} else {
+			fmt.Fprintf(b, ".reloc %d %s\n", r.Offset, r.Symbol)
+		}
+	}
+
+	writeCode(b, o.Code)
+
+	written, err := io.WriteString(w, b.String())
+	return int64(written), err
+}
+
+// writeCode writes comma-separated values, a few per line
+func writeCode(b *strings.Builder, code []int) {
+	for idx, x := range code {
+		b.WriteString(strconv.Itoa(x))
+		if idx == len(code)-1 || idx%16 == 15 {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(',')
+		}
+	}
+}
+
+// Executable is the result of linking objects together
+type Executable struct {
+	Memory  []int
+	Bases   map[string]int // Object name to its base address
+	Symbols map[string]int // Symbol name to its absolute address
+}
+
+// WriteTo writes the memory in the format understood by NewInterpreter
+func (e *Executable) WriteTo(w io.Writer) (n int64, err error) {
+	b := &strings.Builder{}
+	for idx, x := range e.Memory {
+		if idx > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(x))
+	}
+	b.WriteByte('\n')
+
+	written, err := io.WriteString(w, b.String())
+	return int64(written), err
+}
+
+// Link lays out the objects in memory one after another, in the provided order,
+// and patches all relocations. Execution starts at address 0, which is the start of the first object.
+func Link(objects ...*Object) (*Executable, error) {
+	e := &Executable{Bases: make(map[string]int), Symbols: make(map[string]int)}
+
+	// Lay out the objects and collect the symbols
+	for _, o := range objects {
+		if err := o.Validate(); err != nil {
+			return nil, err
+		} else if _, dup := e.Bases[o.Name]; dup {
+			return nil, fmt.Errorf("%w: object %s linked twice", ErrDuplicateSymbol, o.Name)
+		}
+
+		base := len(e.Memory)
+		e.Bases[o.Name] = base
+		e.Memory = append(e.Memory, o.Code...)
+
+		for sym, offset := range o.Exports {
+			if _, dup := e.Symbols[sym]; dup {
+				return nil, fmt.Errorf("%w: %s exported by %s", ErrDuplicateSymbol, sym, o.Name)
+			}
+			e.Symbols[sym] = base + offset
+		}
+	}
+
+	if _, dup := e.Symbols[EndSymbol]; dup {
+		return nil, fmt.Errorf("%w: %s is reserved", ErrDuplicateSymbol, EndSymbol)
+	}
+	e.Symbols[EndSymbol] = len(e.Memory)
+
+	// Patch the relocations
+	for _, o := range objects {
+		base := e.Bases[o.Name]
+		for _, r := range o.Relocations {
+			if r.Symbol == "" {
+				e.Memory[base+r.Offset] += base
+				continue
+			}
+
+			addr, ok := e.Symbols[r.Symbol]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s imported by %s", ErrUndefinedSymbol, r.Symbol, o.Name)
+			}
+			e.Memory[base+r.Offset] += addr
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,114 @@
+package intcode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// linkerMain stores 21 into lib's arg, its return address into lib's ret,
+// calls double and outputs the result
+const linkerMain = `
+.module main
+.import arg
+.import ret
+.import double
+.reloc 3 arg
+.reloc 5
+.reloc 7 ret
+.reloc 10 double
+.reloc 12 arg
+1101,21,0,0,    # ADD 21, 0 -> [arg]
+1101,11,0,0,    # ADD back, 0 -> [ret]
+1105,1,0,       # JNZ 1, double
+4,0,            # back: OUT [arg]
+99
+`
+
+// linkerLib doubles the value in arg and jumps to the address stored in ret
+const linkerLib = `
+.module lib
+.export double 0
+.export arg 7
+.export ret 8
+.reloc 1
+.reloc 3
+.reloc 6
+1002,7,2,7,     # double: MUL [arg], 2 -> [arg]
+105,1,8,        # JNZ 1, [ret]
+0,              # arg
+0               # ret
+`
+
+func mustReadObject(t *testing.T, text string) *Object {
+	t.Helper()
+	o, err := ReadObject(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestLink(t *testing.T) {
+	main := mustReadObject(t, linkerMain)
+	lib := mustReadObject(t, linkerLib)
+
+	e, err := Link(main, lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.Bases["lib"]; got != 14 {
+		t.Errorf("lib linked at %d, want 14", got)
+	}
+	if got := e.Symbols["arg"]; got != 21 {
+		t.Errorf("arg linked at %d, want 21", got)
+	}
+
+	program := &bytes.Buffer{}
+	if _, err := e.WriteTo(program); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, _, state := runSync(program.String(), nil)
+	if state != SyncExecutionStateHalted {
+		t.Errorf("linked program has not halted, state: %d", state)
+	}
+	if !reflect.DeepEqual(outputs, []int{42}) {
+		t.Errorf("got outputs %v, want [42]", outputs)
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	main := mustReadObject(t, linkerMain)
+	lib := mustReadObject(t, linkerLib)
+
+	if _, err := Link(main); !errors.Is(err, ErrUndefinedSymbol) {
+		t.Errorf("expected ErrUndefinedSymbol, got %v", err)
+	}
+
+	libCopy := mustReadObject(t, linkerLib)
+	libCopy.Name = "lib2"
+	if _, err := Link(main, lib, libCopy); !errors.Is(err, ErrDuplicateSymbol) {
+		t.Errorf("expected ErrDuplicateSymbol, got %v", err)
+	}
+
+	if _, err := ReadObject(strings.NewReader(".reloc 1 missing\n1,2")); !errors.Is(err, ErrInvalidObject) {
+		t.Errorf("expected ErrInvalidObject, got %v", err)
+	}
+}
+
+func TestObjectRoundTrip(t *testing.T) {
+	lib := mustReadObject(t, linkerLib)
+
+	text := &bytes.Buffer{}
+	if _, err := lib.WriteTo(text); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mustReadObject(t, text.String()); !reflect.DeepEqual(got, lib) {
+		t.Errorf("round trip changed the object:\n%+v\n%+v", got, lib)
+	}
+}
@@ -0,0 +1,226 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/intmath"
+)
+
+var ErrHeatmapSize = errors.New("heatmap width and scale must be positive")
+
+// MemoryChange describes a memory cell with different values in two snapshots
+type MemoryChange struct {
+	Addr     int
+	Old, New int
+}
+
+func (c MemoryChange) String() string {
+	return fmt.Sprintf("%6d: %d -> %d (%+d)", c.Addr, c.Old, c.New, c.New-c.Old)
+}
+
+// DiffMemory lists all cells which differ between two memory snapshots, in address order.
+// Cells past the end of a snapshot are treated as zero.
+func DiffMemory(before, after []int) (changes []MemoryChange) {
+	for addr := 0; addr < len(before) || addr < len(after); addr++ {
+		old, new := 0, 0
+		if addr < len(before) {
+			old = before[addr]
+		}
+		if addr < len(after) {
+			new = after[addr]
+		}
+
+		if old != new {
+			changes = append(changes, MemoryChange{addr, old, new})
+		}
+	}
+	return
+}
+
+// Snapshotter is a Tracer which follows all memory writes of a program, starting from
+// a copy of its initial memory, and saves snapshots of the memory right before selected
+// inputs are read. Inputs are numbered from zero.
+type Snapshotter struct {
+	Memory    []int         // Current memory of the traced program
+	Snapshots map[int][]int // Input number to the memory before it was read
+	Inputs    int           // Amount of inputs read so far
+
+	wanted map[int]bool
+}
+
+func NewSnapshotter(initial []int, inputs ...int) *Snapshotter {
+	s := &Snapshotter{
+		Memory:    append([]int(nil), initial...),
+		Snapshots: make(map[int][]int),
+		wanted:    make(map[int]bool),
+	}
+	for _, n := range inputs {
+		s.wanted[n] = true
+	}
+	return s
+}
+
+func (s *Snapshotter) Trace(e TraceEntry) {
+	if e.IsInput() {
+		if s.wanted[s.Inputs] {
+			s.Snapshots[s.Inputs] = append([]int(nil), s.Memory...)
+		}
+		s.Inputs++
+	}
+
+	if e.Write >= 0 {
+		if e.Write >= len(s.Memory) {
+			s.Memory = append(s.Memory, make([]int, e.Write-len(s.Memory)+1)...)
+		}
+		s.Memory[e.Write] = e.NewValue
+	}
+}
+
+// HeatmapKind selects which accesses are shown by a MemoryHeatmap
+type HeatmapKind uint8
+
+const (
+	HeatmapReads HeatmapKind = iota
+	HeatmapWrites
+	HeatmapExecutes
+)
+
+// heatmapShades are used to draw terminal grids, from the least to the most accessed cells
+const heatmapShades = " .:-=+*#%@"
+
+// MemoryHeatmap is a Tracer counting how many times every memory cell was read, written
+// and executed (as the first cell of an instruction). Immediate parameters are not counted as reads.
+type MemoryHeatmap struct {
+	Reads    []int
+	Writes   []int
+	Executes []int
+}
+
+func (h *MemoryHeatmap) Trace(e TraceEntry) {
+	ins := e.Instruction
+	written, writes := ins.Writes()
+	for arg := 0; arg < ins.Op.Params(); arg++ {
+		addr, ok := ins.Address(arg, e.RelativeBase)
+		if !ok || addr < 0 || (writes && arg == written) {
+			continue
+		}
+		h.Reads = increment(h.Reads, addr)
+	}
+
+	if e.Write >= 0 {
+		h.Writes = increment(h.Writes, e.Write)
+	}
+	h.Executes = increment(h.Executes, e.IP)
+}
+
+func increment(counts []int, addr int) []int {
+	if addr >= len(counts) {
+		counts = append(counts, make([]int, addr-len(counts)+1)...)
+	}
+	counts[addr]++
+	return counts
+}
+
+// Len returns the amount of addresses covered by the heatmap
+func (h *MemoryHeatmap) Len() int {
+	return intmath.Max(len(h.Reads), len(h.Writes), len(h.Executes))
+}
+
+func (h *MemoryHeatmap) counts(kind HeatmapKind) []int {
+	switch kind {
+	case HeatmapWrites:
+		return h.Writes
+	case HeatmapExecutes:
+		return h.Executes
+	default:
+		return h.Reads
+	}
+}
+
+// intensities scales counts logarithmically into [0, 1], so that rarely accessed cells are still visible.
+// Cells which were never accessed have an intensity of 0, and the most accessed cells 1.
+func intensities(counts []int, length int) []float64 {
+	highest := intmath.Max(counts...)
+
+	scaled := make([]float64, length)
+	for addr, c := range counts {
+		if c > 0 {
+			scaled[addr] = math.Log1p(float64(c)) / math.Log1p(float64(highest))
+		}
+	}
+	return scaled
+}
+
+// WriteGrid draws the heatmap of one kind of access as a grid of characters,
+// with width cells per row. Every row is prefixed by the address of its first cell.
+func (h *MemoryHeatmap) WriteGrid(w io.Writer, kind HeatmapKind, width int) error {
+	if width <= 0 {
+		return fmt.Errorf("%w: width %d", ErrHeatmapSize, width)
+	}
+
+	cells := intensities(h.counts(kind), h.Len())
+	b := &strings.Builder{}
+
+	for row := 0; row < len(cells); row += width {
+		fmt.Fprintf(b, "%6d ", row)
+		for addr := row; addr < row+width && addr < len(cells); addr++ {
+			shade := 0
+			if cells[addr] > 0 {
+				// Accessed cells always get at least the lightest visible shade
+				shade = 1 + int(cells[addr]*float64(len(heatmapShades)-2)+0.5)
+			}
+			b.WriteByte(heatmapShades[shade])
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Image renders the heatmap with width cells per row, every cell being a scale×scale square.
+// Writes are shown in the red channel, reads in green and executes in blue.
+func (h *MemoryHeatmap) Image(width, scale int) *image.RGBA {
+	if width <= 0 || scale <= 0 {
+		panic(fmt.Errorf("%w: width %d, scale %d", ErrHeatmapSize, width, scale))
+	}
+
+	length := h.Len()
+	reads := intensities(h.Reads, length)
+	writes := intensities(h.Writes, length)
+	executes := intensities(h.Executes, length)
+
+	rows := (length + width - 1) / width
+	img := image.NewRGBA(image.Rect(0, 0, width*scale, rows*scale))
+	for addr := 0; addr < length; addr++ {
+		c := color.RGBA{
+			R: uint8(writes[addr] * 255),
+			G: uint8(reads[addr] * 255),
+			B: uint8(executes[addr] * 255),
+			A: 255,
+		}
+
+		x, y := (addr%width)*scale, (addr/width)*scale
+		for dy := 0; dy < scale; dy++ {
+			for dx := 0; dx < scale; dx++ {
+				img.SetRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG encodes the Image of the heatmap as a PNG file
+func (h *MemoryHeatmap) WritePNG(w io.Writer, width, scale int) error {
+	if width <= 0 || scale <= 0 {
+		return fmt.Errorf("%w: width %d, scale %d", ErrHeatmapSize, width, scale)
+	}
+	return png.Encode(w, h.Image(width, scale))
+}
@@ -0,0 +1,63 @@
+package intcode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryProgram reads two values, storing their sum at 20 and product at 21
+const memoryProgram = "3,20,3,21,1,20,21,22,2,20,21,21,99"
+
+func TestSnapshotterDiff(t *testing.T) {
+	i := NewSyncInterpreter(strings.NewReader(memoryProgram))
+	i.Input.PushBack(3)
+	i.Input.PushBack(4)
+
+	s := NewSnapshotter(i.Memory, 1)
+	i.ExecAllTraced(s)
+
+	before, ok := s.Snapshots[1]
+	if !ok {
+		t.Fatal("no snapshot before the second input")
+	}
+
+	got := DiffMemory(before, s.Memory)
+	expected := []MemoryChange{{21, 0, 12}, {22, 0, 7}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+
+	if !reflect.DeepEqual(s.Memory, i.Memory) {
+		t.Errorf("shadow memory %v differs from %v", s.Memory, i.Memory)
+	}
+}
+
+func TestMemoryHeatmap(t *testing.T) {
+	i := NewSyncInterpreter(strings.NewReader(memoryProgram))
+	i.Input.PushBack(3)
+	i.Input.PushBack(4)
+
+	h := &MemoryHeatmap{}
+	i.ExecAllTraced(h)
+
+	if h.Writes[21] != 2 || h.Reads[20] != 2 || h.Executes[12] != 1 {
+		t.Errorf("unexpected counts: writes %v, reads %v, executes %v", h.Writes, h.Reads, h.Executes)
+	}
+
+	b := &strings.Builder{}
+	h.WriteGrid(b, HeatmapWrites, 8)
+	expected := "     0         \n     8         \n    16     *@*\n"
+	if b.String() != expected {
+		t.Errorf("got grid %q, expected %q", b.String(), expected)
+	}
+
+	if err := h.WriteGrid(b, HeatmapWrites, 0); !errors.Is(err, ErrHeatmapSize) {
+		t.Errorf("expected ErrHeatmapSize for width 0, got %v", err)
+	}
+	if err := h.WritePNG(io.Discard, 8, -1); !errors.Is(err, ErrHeatmapSize) {
+		t.Errorf("expected ErrHeatmapSize for scale -1, got %v", err)
+	}
+}
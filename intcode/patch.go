@@ -0,0 +1,200 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+var (
+	ErrPatchAddress  = errors.New("patched address is outside of memory")
+	ErrPatchOriginal = errors.New("patched cell doesn't hold the expected original value")
+	ErrInvalidPatch  = errors.New("invalid patch")
+)
+
+// PatchEntry sets a single memory cell
+type PatchEntry struct {
+	Addr  int
+	Value int
+
+	// If Check is set, the cell must hold Original before it's patched
+	Check    bool
+	Original int
+}
+
+func (e PatchEntry) String() string {
+	if e.Check {
+		return fmt.Sprintf("%d=%d->%d", e.Addr, e.Original, e.Value)
+	}
+	return fmt.Sprintf("%d=%d", e.Addr, e.Value)
+}
+
+// Patch is a set of memory modifications done before running a program.
+//
+// The text representation of an entry is ADDR=VALUE, or ADDR=ORIGINAL->VALUE
+// to ensure the cell holds ORIGINAL before patching it.
+type Patch struct {
+	Name    string
+	Entries []PatchEntry
+}
+
+// String returns the comma-separated entries of the patch, as understood by ParsePatch
+func (p Patch) String() string {
+	entries := make([]string, len(p.Entries))
+	for idx, e := range p.Entries {
+		entries[idx] = e.String()
+	}
+	return strings.Join(entries, ",")
+}
+
+func parsePatchEntry(s string) (e PatchEntry, err error) {
+	s = strings.ReplaceAll(s, " ", "")
+	addr, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return e, fmt.Errorf("%w: %q: expected ADDR=VALUE", ErrInvalidPatch, s)
+	}
+
+	if original, newValue, checked := strings.Cut(value, "->"); checked {
+		e.Check = true
+		value = newValue
+		if e.Original, err = strconv.Atoi(original); err != nil {
+			return e, fmt.Errorf("%w: %q: %v", ErrInvalidPatch, s, err)
+		}
+	}
+
+	if e.Addr, err = strconv.Atoi(addr); err != nil {
+		return e, fmt.Errorf("%w: %q: %v", ErrInvalidPatch, s, err)
+	} else if e.Value, err = strconv.Atoi(value); err != nil {
+		return e, fmt.Errorf("%w: %q: %v", ErrInvalidPatch, s, err)
+	}
+	return e, nil
+}
+
+// ParsePatch parses comma-separated patch entries, like "1=12,2=2" or "0=1->2".
+func ParsePatch(s string) (p Patch, err error) {
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		e, err := parsePatchEntry(entry)
+		if err != nil {
+			return p, err
+		}
+		p.Entries = append(p.Entries, e)
+	}
+	return p, nil
+}
+
+// ReadPatches reads a patch file, which has one entry per line and may define named patches:
+//
+//	# comments start with a hash
+//	[free-play]
+//	0=1->2
+//
+// Entries before the first [name] header belong to a patch with an empty name.
+func ReadPatches(r io.Reader) (patches []Patch, err error) {
+	var current *Patch
+
+	for lineNo, line := range input.ReadLines(r) {
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		} else if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			patches = append(patches, Patch{Name: strings.TrimSpace(line[1 : len(line)-1])})
+			current = &patches[len(patches)-1]
+			continue
+		}
+
+		e, err := parsePatchEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("patch line %d: %w", lineNo+1, err)
+		}
+
+		if current == nil {
+			patches = append(patches, Patch{})
+			current = &patches[len(patches)-1]
+		}
+		current.Entries = append(current.Entries, e)
+	}
+
+	return patches, nil
+}
+
+// Apply modifies the memory. Nothing is modified if any entry is outside of memory,
+// or any checked cell doesn't hold its expected original value.
+func (p Patch) Apply(memory []int) error {
+	for _, e := range p.Entries {
+		if e.Addr < 0 || e.Addr >= len(memory) {
+			return fmt.Errorf("patch %s: %w: %d", p.Name, ErrPatchAddress, e.Addr)
+		} else if e.Check && memory[e.Addr] != e.Original {
+			return fmt.Errorf("patch %s: %w: [%d] is %d, expected %d",
+				p.Name, ErrPatchOriginal, e.Addr, memory[e.Addr], e.Original)
+		}
+	}
+
+	for _, e := range p.Entries {
+		memory[e.Addr] = e.Value
+	}
+	return nil
+}
+
+// Except returns the patch without the entries for cells which are also set by any of the others,
+// so that the others may be applied first without being overridden or failing the checks.
+func (p Patch) Except(others ...Patch) Patch {
+	covered := make(map[int]bool)
+	for _, o := range others {
+		for _, e := range o.Entries {
+			covered[e.Addr] = true
+		}
+	}
+
+	rest := Patch{Name: p.Name}
+	for _, e := range p.Entries {
+		if !covered[e.Addr] {
+			rest.Entries = append(rest.Entries, e)
+		}
+	}
+	return rest
+}
+
+// ApplyPatches applies patches one after another, so that later patches may check values set by earlier ones.
+// Just like with Apply, nothing is modified if any of the patches fails.
+func ApplyPatches(memory []int, patches ...Patch) error {
+	patched := append([]int(nil), memory...)
+	for _, p := range patches {
+		if err := p.Apply(patched); err != nil {
+			return err
+		}
+	}
+	copy(memory, patched)
+	return nil
+}
+
+// ApplyPatch applies all provided patches to the memory of the interpreter (see ApplyPatches).
+func (i *Interpreter) ApplyPatch(patches ...Patch) error { return ApplyPatches(i.Memory, patches...) }
+
+// ApplyPatch applies all provided patches to the memory of the interpreter (see ApplyPatches).
+func (i *SyncInterpreter) ApplyPatch(patches ...Patch) error {
+	return ApplyPatches(i.Memory, patches...)
+}
+
+// NewInterpreterPatched creates an Interpreter (without IO channels), and applies the patches to its memory.
+func NewInterpreterPatched(program io.Reader, patches ...Patch) (*Interpreter, error) {
+	i := NewInterpreter(program)
+	return i, i.ApplyPatch(patches...)
+}
+
+// NewSyncInterpreterPatched creates a SyncInterpreter, and applies the patches to its memory.
+func NewSyncInterpreterPatched(program io.Reader, patches ...Patch) (*SyncInterpreter, error) {
+	i := NewSyncInterpreter(program)
+	return i, i.ApplyPatch(patches...)
+}
@@ -0,0 +1,110 @@
+package intcode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPatches(t *testing.T) {
+	text := `
+# day 2
+1=12
+[free-play]
+0 = 1->2  # checked
+[empty]
+`
+	got, err := ReadPatches(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Patch{
+		{Entries: []PatchEntry{{Addr: 1, Value: 12}}},
+		{Name: "free-play", Entries: []PatchEntry{{Addr: 0, Value: 2, Check: true, Original: 1}}},
+		{Name: "empty"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+
+	if s := got[1].String(); s != "0=1->2" {
+		t.Errorf("got %q, expected \"0=1->2\"", s)
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	p, err := ParsePatch("0=1->2,3=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memory := []int{1, 0, 0, 0}
+	if err := p.Apply(memory); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(memory, []int{2, 0, 0, 7}) {
+		t.Errorf("got memory %v", memory)
+	}
+
+	// The original value is now wrong - nothing should be modified
+	memory[3] = 0
+	if err := p.Apply(memory); !errors.Is(err, ErrPatchOriginal) {
+		t.Errorf("expected ErrPatchOriginal, got %v", err)
+	} else if memory[3] != 0 {
+		t.Error("memory modified by a failed patch")
+	}
+
+	if err := (Patch{Entries: []PatchEntry{{Addr: 4, Value: 1}}}).Apply(memory); !errors.Is(err, ErrPatchAddress) {
+		t.Errorf("expected ErrPatchAddress, got %v", err)
+	}
+
+	if _, err := ParsePatch("0=x"); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	first, _ := ParsePatch("0=1->2")
+	second, _ := ParsePatch("0=2->3,1=5")
+	conflicting, _ := ParsePatch("1=9,2=1->4")
+
+	memory := []int{1, 0, 0}
+	if err := ApplyPatches(memory, first, second); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(memory, []int{3, 5, 0}) {
+		t.Errorf("got memory %v", memory)
+	}
+
+	// A conflict in a later patch must undo the earlier ones
+	memory = []int{1, 0, 0}
+	if err := ApplyPatches(memory, first, conflicting); !errors.Is(err, ErrPatchOriginal) {
+		t.Errorf("expected ErrPatchOriginal, got %v", err)
+	} else if !reflect.DeepEqual(memory, []int{1, 0, 0}) {
+		t.Errorf("memory modified by failed patches: %v", memory)
+	}
+}
+
+func TestPatchExcept(t *testing.T) {
+	builtin, _ := ParsePatch("0=1->2,1=12,2=2")
+	user, _ := ParsePatch("0=5,2=0")
+
+	// The user patch is applied to the program first, and the built-in one must not undo it
+	memory := []int{1, 0, 0}
+	if err := user.Apply(memory); err != nil {
+		t.Fatal(err)
+	}
+	if err := builtin.Except(user).Apply(memory); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(memory, []int{5, 12, 0}) {
+		t.Errorf("got memory %v", memory)
+	}
+
+	// Without the user patch, the built-in one is applied as a whole
+	memory = []int{1, 0, 0}
+	if err := builtin.Except().Apply(memory); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(memory, []int{2, 12, 2}) {
+		t.Errorf("got memory %v", memory)
+	}
+}
@@ -0,0 +1,212 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownMachine = errors.New("unknown machine")
+	ErrMachineHalted  = errors.New("machine has halted")
+)
+
+type MachineState uint8
+
+const (
+	MachineReady MachineState = iota
+	MachineBlocked
+	MachineHalted
+)
+
+func (s MachineState) String() string {
+	switch s {
+	case MachineReady:
+		return "ready"
+	case MachineBlocked:
+		return "blocked"
+	case MachineHalted:
+		return "halted"
+	default:
+		return "unknown"
+	}
+}
+
+// Scheduler cooperatively runs a set of SyncInterpreters, identified by the order they were added.
+//
+// Execution happens in rounds. In every round each ready machine gets a turn, during which it runs
+// until it blocks on input, halts, or executes Quantum instructions. Turns are taken one after
+// another, or in parallel if Workers is greater than 1. After all turns of a round,
+// the outputs of machines are handled by Output, in the order of machine ids,
+// so both modes behave exactly the same.
+//
+// Sending input to a blocked machine wakes it up - it gets a turn in the next round.
+// The scheduler is idle once no machine is ready.
+type Scheduler struct {
+	Machines []*SyncInterpreter
+	States   []MachineState
+
+	// Workers is the amount of machines which may run in parallel, 0 or 1 runs them one after another
+	Workers int
+
+	// Quantum is the maximum amount of instructions executed in a single turn, 0 for no limit
+	Quantum int
+
+	// Output is called after every turn of a machine, and should take values from its output queue.
+	// It may Send inputs to other machines.
+	Output func(id int, m *SyncInterpreter)
+
+	// Wake is optionally called when input is sent to a blocked machine
+	Wake func(id int)
+
+	// Round is the amount of completed rounds
+	Round int
+
+	ready  []int
+	queued []bool
+}
+
+func NewScheduler() *Scheduler { return &Scheduler{} }
+
+// Add adds a ready machine to the scheduler, returning its id
+func (s *Scheduler) Add(m *SyncInterpreter) (id int) {
+	id = len(s.Machines)
+	s.Machines = append(s.Machines, m)
+	s.States = append(s.States, MachineReady)
+	s.queued = append(s.queued, false)
+
+	if m.Halted {
+		s.States[id] = MachineHalted
+	} else {
+		s.enqueue(id)
+	}
+	return
+}
+
+func (s *Scheduler) enqueue(id int) {
+	if !s.queued[id] {
+		s.queued[id] = true
+		s.ready = append(s.ready, id)
+	}
+}
+
+// Send appends values to the input of a machine, waking it up if it was blocked
+func (s *Scheduler) Send(id int, values ...int) error {
+	if id < 0 || id >= len(s.Machines) {
+		return fmt.Errorf("%w: %d", ErrUnknownMachine, id)
+	} else if s.States[id] == MachineHalted {
+		return fmt.Errorf("%w: %d", ErrMachineHalted, id)
+	}
+
+	for _, x := range values {
+		s.Machines[id].Input.PushBack(x)
+	}
+
+	if s.States[id] == MachineBlocked {
+		s.States[id] = MachineReady
+		s.enqueue(id)
+		if s.Wake != nil {
+			s.Wake(id)
+		}
+	}
+	return nil
+}
+
+// Idle returns true if no machine is ready to run
+func (s *Scheduler) Idle() bool { return len(s.ready) == 0 }
+
+// InState returns ids of all machines in the provided state
+func (s *Scheduler) InState(state MachineState) (ids []int) {
+	for id, st := range s.States {
+		if st == state {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
+// Step executes a single round. Returns false if the scheduler was idle.
+func (s *Scheduler) Step() bool {
+	if s.Idle() {
+		return false
+	}
+
+	turns := s.ready
+	s.ready = nil
+	for _, id := range turns {
+		s.queued[id] = false
+	}
+
+	results := make([]SyncExecutionState, len(turns))
+	if s.Workers > 1 {
+		s.runParallel(turns, results)
+	} else {
+		for idx, id := range turns {
+			results[idx] = s.turn(id)
+		}
+	}
+
+	for idx, id := range turns {
+		switch results[idx] {
+		case SyncExecutionStateReady:
+			s.enqueue(id)
+		case SyncExecutionStateBlockedOnInput:
+			s.States[id] = MachineBlocked
+		case SyncExecutionStateHalted:
+			s.States[id] = MachineHalted
+		}
+	}
+
+	if s.Output != nil {
+		sorted := append([]int(nil), turns...)
+		sort.Ints(sorted)
+		for _, id := range sorted {
+			s.Output(id, s.Machines[id])
+		}
+	}
+
+	s.Round++
+	return true
+}
+
+// Run executes rounds until the scheduler is idle
+func (s *Scheduler) Run() {
+	for s.Step() {
+	}
+}
+
+func (s *Scheduler) turn(id int) SyncExecutionState {
+	m := s.Machines[id]
+	if s.Quantum <= 0 {
+		return m.ExecAll()
+	}
+
+	for n := 0; n < s.Quantum; n++ {
+		if state := m.ExecOne(); state != SyncExecutionStateReady {
+			return state
+		}
+	}
+	return SyncExecutionStateReady
+}
+
+func (s *Scheduler) runParallel(turns []int, results []SyncExecutionState) {
+	indices := make(chan int)
+	wg := &sync.WaitGroup{}
+
+	for w := 0; w < s.Workers && w < len(turns); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				results[idx] = s.turn(turns[idx])
+			}
+		}()
+	}
+
+	for idx := range turns {
+		indices <- idx
+	}
+	close(indices)
+	wg.Wait()
+}
@@ -0,0 +1,62 @@
+package intcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// incrementProgram outputs every input value incremented by one
+const incrementProgram = "3,11,1001,11,1,11,4,11,1105,1,0,0"
+
+func TestScheduler(t *testing.T) {
+	for _, workers := range []int{0, 4} {
+		s := NewScheduler()
+		s.Workers = workers
+		for i := 0; i < 2; i++ {
+			s.Add(NewSyncInterpreter(strings.NewReader(incrementProgram)))
+		}
+
+		// Pass the value back and forth until it reaches 100
+		last, wakeups := 0, 0
+		s.Wake = func(int) { wakeups++ }
+		s.Output = func(id int, m *SyncInterpreter) {
+			for m.Output.Len() > 0 {
+				last = m.Output.PopFront()
+				if last < 100 {
+					s.Send(1-id, last)
+				}
+			}
+		}
+
+		s.Send(0, 0)
+		s.Run()
+
+		if last != 100 {
+			t.Errorf("workers %d: got %d, expected 100", workers, last)
+		}
+		if !s.Idle() || len(s.InState(MachineBlocked)) != 2 {
+			t.Errorf("workers %d: expected both machines to be blocked, got %v", workers, s.States)
+		}
+		// Every round increments the value once
+		if s.Round != 100 || wakeups != 99 {
+			t.Errorf("workers %d: got %d rounds and %d wakeups, expected 100 and 99", workers, s.Round, wakeups)
+		}
+	}
+}
+
+func TestSchedulerSendErrors(t *testing.T) {
+	s := NewScheduler()
+	s.Add(NewSyncInterpreter(strings.NewReader("99")))
+	s.Run()
+
+	if s.States[0] != MachineHalted {
+		t.Errorf("expected the machine to halt, got %v", s.States[0])
+	}
+	if err := s.Send(0, 1); !errors.Is(err, ErrMachineHalted) {
+		t.Errorf("expected ErrMachineHalted, got %v", err)
+	}
+	if err := s.Send(1, 1); !errors.Is(err, ErrUnknownMachine) {
+		t.Errorf("expected ErrUnknownMachine, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package intcode
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/deque"
+	"github.com/MKuranowski/AdventOfCode2019/util/gheap"
+)
+
+type SearchStrategy uint8
+
+const (
+	SearchBFS SearchStrategy = iota
+	SearchDFS
+	SearchBestFirst
+)
+
+// SearchNode is a single state of a machine explored by a Search.
+type SearchNode[S any] struct {
+	// M is the machine after executing Move. It's released (set to nil)
+	// after the node is expanded or cut off by MaxDepth, unless the node is a goal.
+	M *SyncInterpreter
+
+	State  S
+	Parent *SearchNode[S]
+	Move   []int // Input which led from Parent to this node
+	Output []int // Output produced in response to Move
+	Depth  int
+}
+
+// Path returns all moves leading from the root to this node
+func (n *SearchNode[S]) Path() (path [][]int) {
+	for ; n.Parent != nil; n = n.Parent {
+		path = append(path, n.Move)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return
+}
+
+// Search explores the states of an intcode machine by cloning it for every possible move.
+// Moves and Update are required, other callbacks are optional.
+type Search[S any] struct {
+	Strategy SearchStrategy
+
+	// Moves lists the inputs which can be sent to the machine in a given node.
+	Moves func(n *SearchNode[S]) [][]int
+
+	// Update sets the State of a new child node, which already has its M, Move and Output set;
+	// child.State starts as a copy of parent.State. Returning false prunes the child.
+	Update func(parent, child *SearchNode[S]) bool
+
+	// Goal checks whether a node is a goal state. Goal nodes are not expanded further.
+	Goal func(n *SearchNode[S]) bool
+
+	// Key returns a comparable value identifying equivalent nodes, which are only visited once.
+	// Defaults to the MemoryHash of the machine.
+	Key func(n *SearchNode[S]) any
+
+	// Priority orders the nodes for SearchBestFirst, lower values are expanded first.
+	Priority func(n *SearchNode[S]) int
+
+	MaxDepth  int // Nodes at this depth are visited, but not expanded; 0 for no limit
+	MaxStates int // Stop after expanding this many nodes; 0 for no limit
+	MaxSteps  int // Prune moves which execute more instructions than this; 0 for no limit
+	MaxGoals  int // Stop after finding this many goals; 0 for no limit
+}
+
+type SearchResult[S any] struct {
+	Goals     []*SearchNode[S]
+	Expanded  int  // Amount of expanded nodes
+	Visited   int  // Amount of unique nodes
+	MaxDepth  int  // Depth of the deepest visited node
+	Exhausted bool // True if every reachable node was visited, without hitting MaxDepth or MaxStates
+}
+
+// MemoryHash returns a hash of the machine's memory and registers.
+// Trailing zeros in memory are ignored.
+func MemoryHash(m *SyncInterpreter) uint64 {
+	end := len(m.Memory)
+	for end > 0 && m.Memory[end-1] == 0 {
+		end--
+	}
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	write := func(x int) {
+		binary.LittleEndian.PutUint64(buf, uint64(x))
+		h.Write(buf)
+	}
+
+	write(m.IP)
+	write(m.RelativeBase)
+	for _, x := range m.Memory[:end] {
+		write(x)
+	}
+	return h.Sum64()
+}
+
+// runUntilBlocked executes the machine until it halts or blocks on input,
+// returning false if that takes more than maxSteps instructions.
+func runUntilBlocked(m *SyncInterpreter, maxSteps int) bool {
+	start := m.Steps
+	state := SyncExecutionStateReady
+	for state == SyncExecutionStateReady {
+		if maxSteps > 0 && m.Steps-start > maxSteps {
+			return false
+		}
+		state = m.ExecOne()
+	}
+	return true
+}
+
+func drainOutput(m *SyncInterpreter) (output []int) {
+	for m.Output.Len() > 0 {
+		output = append(output, m.Output.PopFront())
+	}
+	return
+}
+
+// frontier hides the differences between the search strategies
+type frontier[S any] interface {
+	Len() int
+	Push(*SearchNode[S])
+	Pop() *SearchNode[S]
+}
+
+type fifoFrontier[S any] struct{ deque.Deque[*SearchNode[S]] }
+
+func (f fifoFrontier[S]) Push(n *SearchNode[S]) { f.PushBack(n) }
+func (f fifoFrontier[S]) Pop() *SearchNode[S]   { return f.PopFront() }
+
+type lifoFrontier[S any] struct{ deque.Deque[*SearchNode[S]] }
+
+func (f lifoFrontier[S]) Push(n *SearchNode[S]) { f.PushBack(n) }
+func (f lifoFrontier[S]) Pop() *SearchNode[S]   { return f.PopBack() }
+
+func (s *Search[S]) newFrontier() frontier[S] {
+	switch s.Strategy {
+	case SearchDFS:
+		return lifoFrontier[S]{deque.NewDeque[*SearchNode[S]]()}
+	case SearchBestFirst:
+		return gheap.NewGenericHeap(func(a, b *SearchNode[S]) bool {
+			return s.Priority(a) < s.Priority(b)
+		})
+	default:
+		return fifoFrontier[S]{deque.NewDeque[*SearchNode[S]]()}
+	}
+}
+
+func (s *Search[S]) key(n *SearchNode[S]) any {
+	if s.Key != nil {
+		return s.Key(n)
+	}
+	return MemoryHash(n.M)
+}
+
+// Run explores the states reachable from machine m, whose initial state is described by initial.
+// m itself is not modified; the root node starts with m executed until it blocks on input.
+func (s *Search[S]) Run(m *SyncInterpreter, initial S) (r SearchResult[S]) {
+	root := &SearchNode[S]{M: m.Clone(), State: initial}
+	runUntilBlocked(root.M, 0)
+	root.Output = drainOutput(root.M)
+
+	seen := map[any]struct{}{s.key(root): {}}
+	r.Visited = 1
+	if s.Goal != nil && s.Goal(root) {
+		r.Goals = append(r.Goals, root)
+		return
+	}
+
+	q := s.newFrontier()
+	if !root.M.Halted {
+		q.Push(root)
+	}
+	depthLimited := false
+
+	for q.Len() > 0 {
+		if s.MaxStates > 0 && r.Expanded >= s.MaxStates {
+			return
+		}
+
+		n := q.Pop()
+		r.Expanded++
+
+		for _, move := range s.Moves(n) {
+			child := &SearchNode[S]{M: n.M.Clone(), State: n.State, Parent: n, Move: move, Depth: n.Depth + 1}
+			for _, x := range move {
+				child.M.Input.PushBack(x)
+			}
+			if !runUntilBlocked(child.M, s.MaxSteps) {
+				continue
+			}
+			child.Output = drainOutput(child.M)
+
+			if !s.Update(n, child) {
+				continue
+			}
+
+			k := s.key(child)
+			if _, visited := seen[k]; visited {
+				continue
+			}
+			seen[k] = struct{}{}
+			r.Visited++
+			if child.Depth > r.MaxDepth {
+				r.MaxDepth = child.Depth
+			}
+
+			if s.Goal != nil && s.Goal(child) {
+				r.Goals = append(r.Goals, child)
+				if s.MaxGoals > 0 && len(r.Goals) >= s.MaxGoals {
+					return
+				}
+			} else if s.MaxDepth > 0 && child.Depth >= s.MaxDepth {
+				depthLimited = true
+				child.M = nil
+			} else if !child.M.Halted {
+				q.Push(child)
+			}
+		}
+
+		n.M = nil
+	}
+
+	r.Exhausted = !depthLimited
+	return
+}
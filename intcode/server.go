@@ -0,0 +1,187 @@
+package intcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ServerCodec uint8
+
+const (
+	// CodecASCII turns every received byte into an input value, and every output value into a byte.
+	// Non-ASCII output values are sent as decimal numbers on separate lines.
+	CodecASCII ServerCodec = iota
+
+	// CodecNumbers reads input values as decimal numbers separated by whitespace or commas,
+	// and sends output values as decimal numbers on separate lines.
+	CodecNumbers
+)
+
+// Server exposes an intcode program over a stream socket, like TCP or Unix.
+// Every connection starts its own session with a fresh clone of Program.
+type Server struct {
+	Program *Interpreter
+	Codec   ServerCodec
+}
+
+// Serve accepts connections on l until l is closed
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	i := s.Program.Clone()
+	i.Input = make(chan int)
+	i.Output = make(chan int, 256)
+	done := make(chan struct{})
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go s.receive(conn, i.Input, done, wg)
+	go func() {
+		s.send(conn, i.Output, wg)
+		conn.Close()
+	}()
+
+	if err := execSession(i); err != nil {
+		fmt.Fprintf(os.Stderr, "intcode session %s: %v\n", conn.RemoteAddr(), err)
+	}
+
+	// Wait for all output to be sent, then unblock the receiver
+	close(done)
+	wg.Wait()
+}
+
+// execSession runs the interpreter until it halts or panics,
+// ensuring that the output channel is closed in both cases.
+func execSession(i *Interpreter) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			close(i.Output)
+
+			if pErr, ok := p.(error); ok && errors.Is(pErr, ErrInputOverClosed) {
+				// Client has disconnected while the program waited for input
+				err = nil
+			} else {
+				err = fmt.Errorf("program crashed: %v", p)
+			}
+		}
+	}()
+
+	i.ExecAll()
+	return nil
+}
+
+// receive decodes client data into the input channel. The channel is closed
+// once the client disconnects. Values received after done is closed are dropped.
+func (s *Server) receive(r io.Reader, ch chan<- int, done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	var values <-chan int
+	switch s.Codec {
+	case CodecNumbers:
+		values = decodeNumbers(r)
+	default:
+		values = decodeASCII(r)
+	}
+
+	defer close(ch)
+	for x := range values {
+		select {
+		case ch <- x:
+		case <-done:
+			// Drain the decoder, which stops once the connection is closed
+			for range values {
+			}
+			return
+		}
+	}
+}
+
+func decodeASCII(r io.Reader) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		br := bufio.NewReader(r)
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				return
+			}
+			ch <- int(c)
+		}
+	}()
+	return ch
+}
+
+func decodeNumbers(r io.Reader) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(r)
+		sc.Split(bufio.ScanWords)
+		for sc.Scan() {
+			for _, field := range strings.Split(sc.Text(), ",") {
+				if field == "" {
+					continue
+				}
+
+				x, err := strconv.Atoi(field)
+				if err != nil {
+					continue
+				}
+				ch <- x
+			}
+		}
+	}()
+	return ch
+}
+
+// send encodes all values from the output channel for the client
+func (s *Server) send(w io.Writer, ch <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	bw := bufio.NewWriter(w)
+	failed := false
+	for x := range ch {
+		if failed {
+			// Keep draining the channel, so that the program doesn't get stuck
+			continue
+		}
+
+		var err error
+		if s.Codec == CodecASCII && x >= 0 && x < 0x80 {
+			err = bw.WriteByte(byte(x))
+		} else {
+			_, err = fmt.Fprintln(bw, x)
+		}
+
+		// Flush whenever the program might wait for a response
+		if err == nil && (s.Codec != CodecASCII || x == '\n' || len(ch) == 0) {
+			err = bw.Flush()
+		}
+		failed = err != nil
+	}
+
+	if !failed {
+		bw.Flush()
+	}
+}
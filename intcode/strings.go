@@ -0,0 +1,211 @@
+package intcode
+
+import (
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/ascii"
+)
+
+type StringEncoding uint8
+
+const (
+	// StringPlain is a run of ASCII characters
+	StringPlain StringEncoding = iota
+
+	// StringLengthPrefixed is the length of the string followed by ASCII characters
+	StringLengthPrefixed
+
+	// StringObfuscated is the length of the string (n) followed by characters,
+	// where the i-th (zero-based) character is stored as c-n-i.
+	StringObfuscated
+)
+
+func (e StringEncoding) String() string {
+	switch e {
+	case StringPlain:
+		return "plain"
+	case StringLengthPrefixed:
+		return "length-prefixed"
+	case StringObfuscated:
+		return "obfuscated"
+	default:
+		return "unknown"
+	}
+}
+
+// EmbeddedString is an ASCII string found in the memory of a program
+type EmbeddedString struct {
+	Addr     int // Address of the first character, or of the length prefix
+	Text     string
+	Encoding StringEncoding
+}
+
+func isPrintable(c int) bool { return (c >= ' ' && c <= '~') || c == '\n' }
+
+// looksLikeText filters out random code and data which happens to be printable.
+// Text needs to be at least half letters (a fifth of them vowels),
+// with few unusual symbols and no "mIxEd" case words.
+func looksLikeText(s string) bool {
+	letters, vowels, unusual := 0, 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case ascii.IsUpper(c):
+			if i > 0 && ascii.IsLower(s[i-1]) {
+				return false
+			}
+			letters++
+		case ascii.IsLower(c):
+			letters++
+		case c >= '0' && c <= '9', strings.IndexByte(" \n.,:;!?'\"-()", c) >= 0:
+			// Digits and common punctuation
+		default:
+			unusual++
+		}
+
+		if strings.IndexByte("aeiouy", ascii.ToLower(c)) >= 0 {
+			vowels++
+		}
+	}
+	return 2*letters >= len(s) && 5*vowels >= letters && 10*unusual <= len(s)
+}
+
+// decodeLengthPrefixed tries to decode a length-prefixed string starting at memory[at].
+// With obfuscated set, the i-th character is decoded as memory[at+1+i] + length + i.
+func decodeLengthPrefixed(memory []int, at int, obfuscated bool) (string, bool) {
+	length := memory[at]
+	if length <= 0 || at+length >= len(memory) {
+		return "", false
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < length; i++ {
+		c := memory[at+1+i]
+		if obfuscated {
+			c += length + i
+		}
+
+		if !isPrintable(c) {
+			return "", false
+		}
+		b.WriteByte(byte(c))
+	}
+	return b.String(), true
+}
+
+// FindStrings statically scans memory for embedded strings of at least minLen characters.
+// Length-prefixed strings are preferred over plain runs of printable characters.
+func FindStrings(memory []int, minLen int) (found []EmbeddedString) {
+	for at := 0; at < len(memory); {
+		// Try the length-prefixed encodings
+		matched := false
+		for _, enc := range []StringEncoding{StringLengthPrefixed, StringObfuscated} {
+			text, ok := decodeLengthPrefixed(memory, at, enc == StringObfuscated)
+			if ok && len(text) >= minLen && looksLikeText(text) {
+				found = append(found, EmbeddedString{at, text, enc})
+				at += len(text) + 1
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		// Try a plain run of printable characters
+		end := at
+		for end < len(memory) && isPrintable(memory[end]) {
+			end++
+		}
+
+		if text := asciiString(memory[at:end]); len(text) >= minLen && looksLikeText(text) {
+			found = append(found, EmbeddedString{at, text, StringPlain})
+			at = end
+		} else {
+			at++
+		}
+	}
+	return
+}
+
+func asciiString(values []int) string {
+	b := strings.Builder{}
+	for _, c := range values {
+		b.WriteByte(byte(c))
+	}
+	return b.String()
+}
+
+// OutputString is a line of text printed by a program
+type OutputString struct {
+	Text string
+	Step int   // Step of the first character's OUT instruction
+	IPs  []int // Distinct addresses of the OUT instructions which printed the text
+}
+
+// StringCollector is a Tracer which splits all ASCII output of a program into lines,
+// remembering which instructions have printed them. Non-ASCII outputs end the current line.
+type StringCollector struct {
+	Strings []OutputString
+	current *OutputString
+	text    strings.Builder
+}
+
+func (c *StringCollector) Trace(e TraceEntry) {
+	if !e.IsOutput() {
+		return
+	}
+
+	if !isPrintable(e.Output) {
+		c.Flush()
+		return
+	}
+
+	if c.current == nil {
+		c.current = &OutputString{Step: e.Step}
+	}
+	if !containsInt(c.current.IPs, e.IP) {
+		c.current.IPs = append(c.current.IPs, e.IP)
+	}
+
+	if e.Output == '\n' {
+		c.Flush()
+	} else {
+		c.text.WriteByte(byte(e.Output))
+	}
+}
+
+// Flush ends the line which is currently being printed
+func (c *StringCollector) Flush() {
+	if c.current == nil {
+		return
+	}
+
+	c.current.Text = c.text.String()
+	c.Strings = append(c.Strings, *c.current)
+	c.current = nil
+	c.text.Reset()
+}
+
+// Unique returns the collected strings without duplicates and empty lines,
+// in order of their first appearance.
+func (c *StringCollector) Unique() (unique []OutputString) {
+	seen := make(map[string]struct{})
+	for _, s := range c.Strings {
+		if _, dup := seen[s.Text]; dup || s.Text == "" {
+			continue
+		}
+		seen[s.Text] = struct{}{}
+		unique = append(unique, s)
+	}
+	return
+}
+
+func containsInt(xs []int, x int) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package intcode
+
+// TraceEntry describes a single executed instruction.
+type TraceEntry struct {
+	Step         int // Value of Steps before the instruction was executed
+	IP           int
+	RelativeBase int // Relative base before the instruction was executed
+	Instruction  Instruction
+	MemoryLen    int // Length of memory before the instruction was executed
+
+	// Write is the address written by the instruction, or -1 if nothing was written.
+	Write    int
+	OldValue int
+	NewValue int
+
+	// Output is the value sent by an OUT instruction.
+	Output int
+
+	// NextIP is the instruction pointer after the instruction was executed.
+	NextIP int
+}
+
+// IsInput returns true if the entry describes an IN instruction.
+// The value that was read is available in NewValue.
+func (e TraceEntry) IsInput() bool { return e.Instruction.Op == OpIn }
+
+// IsOutput returns true if the entry describes an OUT instruction.
+// The value that was sent is available in Output.
+func (e TraceEntry) IsOutput() bool { return e.Instruction.Op == OpOut }
+
+// Tracer observes every instruction executed through ExecOneTraced or ExecAllTraced.
+type Tracer interface {
+	Trace(e TraceEntry)
+}
+
+// PanicTracer is a Tracer which also wants to be notified when the interpreter panics,
+// for example on an unknown opcode. The panic continues after TracePanic returns.
+type PanicTracer interface {
+	Tracer
+	TracePanic(ip int, p any)
+}
+
+// reportPanic must be deferred directly
+func reportPanic(t PanicTracer, ip int) {
+	if p := recover(); p != nil {
+		t.TracePanic(ip, p)
+		panic(p)
+	}
+}
+
+// Tracers broadcasts every TraceEntry to multiple Tracers.
+type Tracers []Tracer
+
+func (ts Tracers) Trace(e TraceEntry) {
+	for _, t := range ts {
+		t.Trace(e)
+	}
+}
+
+func (ts Tracers) TracePanic(ip int, p any) {
+	for _, t := range ts {
+		if pt, ok := t.(PanicTracer); ok {
+			pt.TracePanic(ip, p)
+		}
+	}
+}
+
+// TracerFunc allows using ordinary functions as Tracers.
+type TracerFunc func(TraceEntry)
+
+func (f TracerFunc) Trace(e TraceEntry) { f(e) }
+
+// beginTrace prepares a TraceEntry for the instruction about to be executed.
+// ok is false if the instruction can't be decoded - the interpreter will panic on it anyway.
+func beginTrace(memory []int, ip, relativeBase, steps int) (e TraceEntry, ok bool) {
+	ins, err := Decode(memory, ip)
+	if err != nil {
+		return e, false
+	}
+
+	e = TraceEntry{
+		Step:         steps,
+		IP:           ip,
+		RelativeBase: relativeBase,
+		Instruction:  ins,
+		MemoryLen:    len(memory),
+		Write:        -1,
+	}
+
+	if addr, ok := ins.WriteAddress(relativeBase); ok && addr >= 0 {
+		e.Write = addr
+		if addr < len(memory) {
+			e.OldValue = memory[addr]
+		}
+	}
+
+	if ins.Op == OpOut {
+		e.Output = ins.Get(0, memory, relativeBase)
+	}
+
+	return e, true
+}
+
+// finishTrace fills in the values which are only known after the instruction was executed.
+func finishTrace(e *TraceEntry, memory []int, ip int) {
+	e.NextIP = ip
+	if e.Write >= 0 {
+		e.NewValue = memory[e.Write]
+	}
+}
+
+// ExecOneTraced works just like ExecOne, but additionally reports the executed instruction to t.
+// If t is nil, this is equivalent to ExecOne.
+func (i *Interpreter) ExecOneTraced(t Tracer) (more bool) {
+	if t == nil || i.IsHalted() {
+		return i.ExecOne()
+	}
+	if pt, ok := t.(PanicTracer); ok {
+		defer reportPanic(pt, i.IP)
+	}
+
+	e, ok := beginTrace(i.Memory, i.IP, i.RelativeBase, i.Steps)
+	more = i.ExecOne()
+	if ok {
+		finishTrace(&e, i.Memory, i.IP)
+		t.Trace(e)
+	}
+	return
+}
+
+// ExecAllTraced works just like ExecAll, but additionally reports every executed instruction to t.
+// If t is nil, this is equivalent to ExecAll.
+func (i *Interpreter) ExecAllTraced(t Tracer) {
+	for i.ExecOneTraced(t) {
+	}
+
+	if i.Output != nil {
+		close(i.Output)
+	}
+}
+
+// ExecOneTraced works just like ExecOne, but additionally reports the executed instruction to t.
+// Nothing is reported if the interpreter is blocked on input.
+// If t is nil, this is equivalent to ExecOne.
+func (i *SyncInterpreter) ExecOneTraced(t Tracer) (state SyncExecutionState) {
+	if t == nil || i.Halted {
+		return i.ExecOne()
+	}
+	if pt, ok := t.(PanicTracer); ok {
+		defer reportPanic(pt, i.IP)
+	}
+
+	e, ok := beginTrace(i.Memory, i.IP, i.RelativeBase, i.Steps)
+	state = i.ExecOne()
+	if ok && i.Steps != e.Step {
+		finishTrace(&e, i.Memory, i.IP)
+		t.Trace(e)
+	}
+	return
+}
+
+// ExecAllTraced works just like ExecAll, but additionally reports every executed instruction to t.
+// If t is nil, this is equivalent to ExecAll.
+func (i *SyncInterpreter) ExecAllTraced(t Tracer) SyncExecutionState {
+	state := SyncExecutionStateReady
+	for state == SyncExecutionStateReady {
+		state = i.ExecOneTraced(t)
+	}
+	return state
+}
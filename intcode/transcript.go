@@ -0,0 +1,184 @@
+package intcode
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MKuranowski/AdventOfCode2019/util/input"
+)
+
+type EventKind uint8
+
+const (
+	EventInput EventKind = iota
+	EventOutput
+)
+
+func (k EventKind) String() string {
+	if k == EventInput {
+		return "in"
+	}
+	return "out"
+}
+
+// Event is a single value consumed or produced by a program,
+// together with the amount of instructions executed before it.
+type Event struct {
+	Kind  EventKind
+	Step  int
+	Value int
+}
+
+func (e Event) String() string { return fmt.Sprintf("%s %d %d", e.Kind, e.Step, e.Value) }
+
+// Transcript is the list of all I/O events of a single program run.
+//
+// The text representation has one event per line, e.g. "in 1520 -1" or "out 1544 72".
+type Transcript []Event
+
+func (t Transcript) WriteTo(w io.Writer) (n int64, err error) {
+	for _, e := range t {
+		written, err := fmt.Fprintln(w, e)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func ReadTranscript(r io.Reader) (t Transcript, err error) {
+	for lineNo, line := range input.ReadLines(r) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var kind string
+		e := Event{}
+		if _, err := fmt.Sscanf(line, "%s %d %d", &kind, &e.Step, &e.Value); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %w", lineNo+1, err)
+		}
+
+		switch kind {
+		case "in":
+			e.Kind = EventInput
+		case "out":
+			e.Kind = EventOutput
+		default:
+			return nil, fmt.Errorf("transcript line %d: invalid event kind: %q", lineNo+1, kind)
+		}
+
+		t = append(t, e)
+	}
+	return t, nil
+}
+
+// Recorder is a Tracer which collects all I/O events into a Transcript.
+// If W is not nil, events are additionally written to it as soon as they happen,
+// so that a transcript survives a program which is interrupted.
+type Recorder struct {
+	Transcript Transcript
+	W          io.Writer
+}
+
+func (r *Recorder) Trace(e TraceEntry) {
+	var ev Event
+	switch {
+	case e.IsInput():
+		ev = Event{EventInput, e.Step, e.NewValue}
+	case e.IsOutput():
+		ev = Event{EventOutput, e.Step, e.Output}
+	default:
+		return
+	}
+
+	r.Transcript = append(r.Transcript, ev)
+	if r.W != nil {
+		if _, err := fmt.Fprintln(r.W, ev); err != nil {
+			panic(fmt.Errorf("failed to write transcript: %w", err))
+		}
+	}
+}
+
+// DivergenceError describes the first point where a replayed program
+// behaves differently than the transcript.
+type DivergenceError struct {
+	Index    int // Index of the expected event in the transcript
+	IP       int
+	Expected *Event // nil if the transcript has no more events
+	Actual   *Event // nil if the program has halted
+}
+
+func (e *DivergenceError) Error() string {
+	expected, actual := "end of transcript", "halt"
+	if e.Expected != nil {
+		expected = e.Expected.String()
+	}
+	if e.Actual != nil {
+		actual = e.Actual.String()
+	}
+	return fmt.Sprintf("replay diverged at event %d (ip %d): expected %q, got %q",
+		e.Index, e.IP, expected, actual)
+}
+
+// Replay runs the program, feeding it inputs from the transcript
+// and ensuring it produces exactly the same events.
+//
+// A replay is successful if the program halts, or blocks on input, right after the last event.
+// The first difference is reported with a *DivergenceError.
+func Replay(i *SyncInterpreter, t Transcript) error {
+	idx := 0
+
+	check := func(ip int, actual Event) error {
+		if idx >= len(t) || t[idx] != actual {
+			err := &DivergenceError{Index: idx, IP: ip, Actual: &actual}
+			if idx < len(t) {
+				err.Expected = &t[idx]
+			}
+			return err
+		}
+		idx++
+		return nil
+	}
+
+	for {
+		ins, err := Decode(i.Memory, i.IP)
+		if err != nil {
+			return fmt.Errorf("replay stopped at event %d: %w", idx, err)
+		}
+
+		switch ins.Op {
+		case OpIn:
+			if idx >= len(t) {
+				// Program waits for more input than was recorded - that's where the recording ended
+				return nil
+			} else if t[idx].Kind != EventInput {
+				return check(i.IP, Event{EventInput, i.Steps, 0})
+			}
+
+			i.Input.PushBack(t[idx].Value)
+			if err := check(i.IP, Event{EventInput, i.Steps, t[idx].Value}); err != nil {
+				return err
+			}
+			i.ExecOne()
+
+		case OpOut:
+			ip, step := i.IP, i.Steps
+			i.ExecOne()
+			if err := check(ip, Event{EventOutput, step, i.Output.PopBack()}); err != nil {
+				return err
+			}
+
+		case OpHalt:
+			if idx < len(t) {
+				return &DivergenceError{Index: idx, IP: i.IP, Expected: &t[idx]}
+			}
+			return nil
+
+		default:
+			i.ExecOne()
+		}
+	}
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MKuranowski/AdventOfCode2019/day01"
 	"github.com/MKuranowski/AdventOfCode2019/day02"
@@ -33,6 +39,9 @@ import (
 	"github.com/MKuranowski/AdventOfCode2019/day23"
 	"github.com/MKuranowski/AdventOfCode2019/day24"
 	"github.com/MKuranowski/AdventOfCode2019/day25"
+	"github.com/MKuranowski/AdventOfCode2019/intcode"
+	"github.com/MKuranowski/AdventOfCode2019/intcode/compiler"
+	"golang.org/x/term"
 )
 
 var solutions = map[string]func(io.Reader) any{
@@ -87,7 +96,164 @@ var solutions = map[string]func(io.Reader) any{
 	"25a": day25.SolveA,
 }
 
+// alternativeSolutions are selected with a suffix after the day number, e.g. "15a/search"
+var alternativeSolutions = map[string]func(io.Reader) any{
+	"15a/search":     day15.SolveASearch,
+	"15b/search":     day15.SolveBSearch,
+	"21a/compiled":   day21.SolveACompiled,
+	"21b/compiled":   day21.SolveBCompiled,
+	"21a/search":     day21.SolveASearch,
+	"21b/search":     day21.SolveBSearch,
+	"23a/parallel":   day23.SolveAParallel,
+	"23b/parallel":   day23.SolveBParallel,
+	"23a/concurrent": day23.SolveAConcurrent,
+	"23b/concurrent": day23.SolveBConcurrent,
+	"25a/search":     day25.SolveASearch,
+	"25a/explore":    day25.SolveAExplore,
+}
+
+var tracedSolutions = map[string]func(io.Reader, intcode.Tracer) any{
+	"13b": day13.SolveBTraced,
+	"15a": day15.SolveATraced,
+	"15b": day15.SolveBTraced,
+	"17a": day17.SolveATraced,
+	"17b": day17.SolveBTraced,
+	"21a": day21.SolveATraced,
+	"21b": day21.SolveBTraced,
+	"25a": day25.SolveATraced,
+}
+
+// solutionPatches lists memory modifications done by solutions before running their programs,
+// which need to be repeated when replaying transcripts or serving the programs.
+// Cells set by userPatches are left out of them (see main).
+var solutionPatches = map[string]*intcode.Patch{
+	"02a": &day02.Alarm1202,
+	"13b": &day13.FreePlay,
+	"17b": &day17.WakeUp,
+}
+
+// solutionPatch returns the memory modifications done by the solution of a day, if there are any
+func solutionPatch(day string) intcode.Patch {
+	if p, ok := solutionPatches[day]; ok {
+		return *p
+	}
+	return intcode.Patch{}
+}
+
+var commands = map[string]func(args []string){
+	"record":       record,
+	"replay":       replay,
+	"strings":      extractStrings,
+	"serve":        serve,
+	"link":         link,
+	"compile":      compile,
+	"memdiff":      memdiff,
+	"heatmap":      heatmap,
+	"calls":        calls,
+	"netcap":       netcap,
+	"netstats":     netstats,
+	"play":         play,
+	"arcade":       arcade,
+	"maze":         maze,
+	"video":        video,
+	"springscript": springscript,
+	"springsearch": springsearch,
+}
+
+// playableDays are interactive sessions started with the play command, receiving arguments after the day number
+var playableDays = map[string]func(args []string){
+	"13": play13,
+	"25": play25,
+}
+
+// patchList collects patches from -patch flags
+type patchList []intcode.Patch
+
+func (l *patchList) String() string {
+	specs := make([]string, len(*l))
+	for idx, p := range *l {
+		specs[idx] = p.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set parses inline patch entries (ADDR=VALUE[,...]), or reads patches from a file.
+// With FILE:NAME only the named patch is used, otherwise all patches from the file.
+func (l *patchList) Set(spec string) error {
+	if strings.Contains(spec, "=") {
+		p, err := intcode.ParsePatch(spec)
+		if err != nil {
+			return err
+		}
+		p.Name = spec
+		*l = append(*l, p)
+		return nil
+	}
+
+	fileName, name, named := strings.Cut(spec, ":")
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	patches, err := intcode.ReadPatches(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	found := false
+	for _, p := range patches {
+		if !named || p.Name == name {
+			*l = append(*l, p)
+			found = true
+		}
+	}
+	if named && !found {
+		return fmt.Errorf("%s: no patch named %q", fileName, name)
+	}
+	return nil
+}
+
+// userPatches are applied to every program loaded with loadInput
+var userPatches patchList
+
+// intcodeDays have intcode programs as their inputs, which can be patched
+var intcodeDays = map[string]bool{
+	"02": true, "05": true, "07": true, "09": true, "11": true, "13": true,
+	"15": true, "17": true, "19": true, "21": true, "23": true, "25": true,
+}
+
+// loadInput opens the input of a day, applying userPatches if there are any
 func loadInput(day string, test bool) io.ReadCloser {
+	if len(userPatches) == 0 {
+		return openInput(day, test)
+	} else if len(day) < 2 || !intcodeDays[day[:2]] {
+		panic(fmt.Errorf("-patch can only be used with intcode programs, and the input of day %q isn't one", day))
+	}
+
+	f := openInput(day, test)
+	defer f.Close()
+
+	i, err := intcode.NewInterpreterPatched(f, userPatches...)
+	if err != nil {
+		panic(err)
+	}
+
+	b := &strings.Builder{}
+	for idx, x := range i.Memory {
+		if idx > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(x))
+	}
+	return io.NopCloser(strings.NewReader(b.String()))
+}
+
+func openInput(day string, test bool) io.ReadCloser {
+	// Alternative solutions share the input
+	day, _, _ = strings.Cut(day, "/")
+
 	// Try to read a file with "a" or "b" suffix
 	var fileName string
 	if test {
@@ -117,16 +283,877 @@ func loadInput(day string, test bool) io.ReadCloser {
 	return f
 }
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [-patch PATCH]... DAY-NUMBER [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s record DAY-NUMBER TRANSCRIPT [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s replay DAY-NUMBER TRANSCRIPT [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s strings [-n MIN-LENGTH] [-dynamic] DAY-NUMBER [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s serve [-unix] [-numbers] DAY-NUMBER ADDRESS [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s link [-o OUTPUT] OBJECT...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s compile [-c] [-o OUTPUT] SOURCE\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s memdiff [-from INPUT] [-to INPUT] DAY-NUMBER [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s heatmap [-kind KIND] [-width CELLS] [-png FILE] DAY-NUMBER [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s calls [-break ADDR,...] [-folded FILE] DAY-NUMBER [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s netcap [-csv] DAY-NUMBER CAPTURE [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s netstats CAPTURE\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s play 13 [-fps FPS] [-autopilot] [-record TRANSCRIPT] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s play 25 [-script COMMANDS] [-transcript FILE] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s arcade [-replay TRANSCRIPT] [-frames N] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s maze [-load MAP] [-save MAP] [-json] [-show] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s video [-fps FPS] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s springscript [-run] [-check HULLS] FORMULA\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s springsearch [-run] [-hulls HULLS] [-rounds N] [-offline] [test]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nPATCH is ADDR=VALUE[,...] (ADDR=ORIGINAL->VALUE checks the original value),\n")
+	fmt.Fprintf(os.Stderr, "or FILE[:NAME] with a patch file (see intcode.ReadPatches). Patches apply to commands, too,\n")
+	fmt.Fprintf(os.Stderr, "but only on days with intcode programs.\n")
+	os.Exit(1)
+}
+
+// record runs a solution, saving all of its intcode I/O into a transcript file
+func record(args []string) {
+	if len(args) != 2 && len(args) != 3 {
+		usage()
+	}
+
+	day := args[0]
+	test := len(args) == 3 && args[2] == "test"
+
+	solver, ok := tracedSolutions[day]
+	if !ok {
+		panic(fmt.Errorf("no traced solver for %q in main.go lookup table", day))
+	}
+
+	f := loadInput(day, test)
+	defer f.Close()
+
+	out, err := os.Create(args[1])
+	if err != nil {
+		panic(fmt.Errorf("failed to create transcript: %w", err))
+	}
+	defer out.Close()
+
+	result := solver(f, &intcode.Recorder{W: out})
+	if result != nil {
+		fmt.Println(result)
+	}
+}
+
+// replay runs the intcode program of a day against a transcript file
+func replay(args []string) {
+	if len(args) != 2 && len(args) != 3 {
+		usage()
+	}
+
+	day := args[0]
+	test := len(args) == 3 && args[2] == "test"
+
+	f := loadInput(day, test)
+	defer f.Close()
+	i, err := intcode.NewSyncInterpreterPatched(f, solutionPatch(day))
+	if err != nil {
+		panic(err)
+	}
+
+	tf, err := os.Open(args[1])
+	if err != nil {
+		panic(fmt.Errorf("failed to open transcript: %w", err))
+	}
+	defer tf.Close()
+
+	t, err := intcode.ReadTranscript(tf)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := intcode.Replay(i, t); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("replayed %d events in %d steps\n", len(t), i.Steps)
+}
+
+// extractStrings lists strings embedded in an intcode program,
+// or with -dynamic, strings printed while running a solution
+func extractStrings(args []string) {
+	flags := flag.NewFlagSet("strings", flag.ExitOnError)
+	minLen := flags.Int("n", 4, "minimum length of reported strings")
+	dynamic := flags.Bool("dynamic", false, "run the solution and report printed lines")
+	flags.Parse(args)
+	if flags.NArg() != 1 && flags.NArg() != 2 {
+		usage()
+	}
+
+	day := flags.Arg(0)
+	test := flags.NArg() == 2 && flags.Arg(1) == "test"
+
+	f := loadInput(day, test)
+	defer f.Close()
+
+	if !*dynamic {
+		i := intcode.NewInterpreter(f)
+		for _, s := range intcode.FindStrings(i.Memory, *minLen) {
+			fmt.Printf("%6d %-15s %q\n", s.Addr, s.Encoding, s.Text)
+		}
+		return
+	}
+
+	solver, ok := tracedSolutions[day]
+	if !ok {
+		panic(fmt.Errorf("no traced solver for %q in main.go lookup table", day))
+	}
+
+	c := &intcode.StringCollector{}
+	solver(f, c)
+	c.Flush()
+
+	for _, s := range c.Unique() {
+		if len(s.Text) >= *minLen {
+			fmt.Printf("%8d %v %q\n", s.Step, s.IPs, s.Text)
+		}
+	}
+}
+
+// serve exposes the intcode program of a day over a TCP or Unix socket
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	unix := flags.Bool("unix", false, "listen on a Unix socket instead of TCP")
+	numbers := flags.Bool("numbers", false, "exchange decimal numbers instead of ASCII")
+	flags.Parse(args)
+	if flags.NArg() != 2 && flags.NArg() != 3 {
+		usage()
+	}
+
+	day := flags.Arg(0)
+	test := flags.NArg() == 3 && flags.Arg(2) == "test"
+
+	f := loadInput(day, test)
+	defer f.Close()
+	program, err := intcode.NewInterpreterPatched(f, solutionPatch(day))
+	if err != nil {
+		panic(err)
+	}
+	s := &intcode.Server{Program: program}
+	if *numbers {
+		s.Codec = intcode.CodecNumbers
+	}
+
+	network := "tcp"
+	if *unix {
+		network = "unix"
+	}
+	l, err := net.Listen(network, flags.Arg(1))
+	if err != nil {
+		panic(fmt.Errorf("failed to listen: %w", err))
+	}
+
+	// Close the listener on Ctrl+C, which also removes Unix sockets
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		l.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", day, l.Addr())
+	if err := s.Serve(l); err != nil {
+		panic(err)
+	}
+}
+
+// link combines intcode object files into a single program
+func link(args []string) {
+	flags := flag.NewFlagSet("link", flag.ExitOnError)
+	output := flags.String("o", "", "output file (defaults to stdout)")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		usage()
+	}
+
+	objects := make([]*intcode.Object, 0, flags.NArg())
+	for _, fileName := range flags.Args() {
+		f, err := os.Open(fileName)
+		if err != nil {
+			panic(fmt.Errorf("failed to open object: %w", err))
+		}
+
+		o, err := intcode.ReadObject(f)
+		f.Close()
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", fileName, err))
+		}
+		objects = append(objects, o)
+	}
+
+	e, err := intcode.Link(objects...)
+	if err != nil {
+		panic(err)
+	}
+
+	writeOutput(*output, e)
+}
+
+// writeOutput writes a program or an object to the provided file, or to stdout if fileName is empty
+func writeOutput(fileName string, x io.WriterTo) {
+	var w io.Writer = os.Stdout
+	if fileName != "" {
+		f, err := os.Create(fileName)
+		if err != nil {
+			panic(fmt.Errorf("failed to create output: %w", err))
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if _, err := x.WriteTo(w); err != nil {
+		panic(fmt.Errorf("failed to write output: %w", err))
+	}
+}
+
+// compile translates a source file into an intcode program, or an object with -c
+func compile(args []string) {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	objectOnly := flags.Bool("c", false, "only compile into an object, don't link")
+	output := flags.String("o", "", "output file (defaults to stdout)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		panic(fmt.Errorf("failed to open source: %w", err))
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(flags.Arg(0)), filepath.Ext(flags.Arg(0)))
+	o, err := compiler.Compile(name, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+
+	if *objectOnly {
+		writeOutput(*output, o)
+		return
+	}
+
+	e, err := intcode.Link(o)
+	if err != nil {
+		panic(err)
+	}
+	writeOutput(*output, e)
+}
+
+// tracedSolver looks up the traced solution of a day from the arguments of a command
+func tracedSolver(flags *flag.FlagSet) (day string, test bool, solver func(io.Reader, intcode.Tracer) any) {
+	if flags.NArg() != 1 && flags.NArg() != 2 {
+		usage()
+	}
+
+	day = flags.Arg(0)
+	test = flags.NArg() == 2 && flags.Arg(1) == "test"
+
+	solver, ok := tracedSolutions[day]
+	if !ok {
+		panic(fmt.Errorf("no traced solver for %q in main.go lookup table", day))
+	}
+	return
+}
+
+// memdiff runs a solution and compares its memory before two of its inputs
+func memdiff(args []string) {
+	flags := flag.NewFlagSet("memdiff", flag.ExitOnError)
+	from := flags.Int("from", 0, "number of the first input (counting from zero)")
+	to := flags.Int("to", -1, "number of the second input (defaults to the one after -from)")
+	flags.Parse(args)
+	day, test, solver := tracedSolver(flags)
+	if *to < 0 {
+		*to = *from + 1
+	}
+
+	f := loadInput(day, test)
+	i, err := intcode.NewInterpreterPatched(f, solutionPatch(day))
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+	initial := i.Memory
+
+	s := intcode.NewSnapshotter(initial, *from, *to)
+	f = loadInput(day, test)
+	defer f.Close()
+	solver(f, s)
+
+	before, ok := s.Snapshots[*from]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "program has only read %d inputs\n", s.Inputs)
+		os.Exit(1)
+	}
+	after, ok := s.Snapshots[*to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "program has only read %d inputs, comparing with its final memory\n", s.Inputs)
+		after = s.Memory
+	}
+
+	for _, c := range intcode.DiffMemory(before, after) {
+		fmt.Println(c)
+	}
+}
+
+// heatmap runs a solution and draws how often every memory cell was accessed
+func heatmap(args []string) {
+	flags := flag.NewFlagSet("heatmap", flag.ExitOnError)
+	kind := flags.String("kind", "writes", "accesses shown in the terminal: reads, writes or executes")
+	width := flags.Int("width", 64, "cells per row")
+	pngFile := flags.String("png", "", "save a PNG (writes in red, reads in green, executes in blue) instead")
+	scale := flags.Int("scale", 4, "size of every cell in the PNG, in pixels")
+	flags.Parse(args)
+	day, test, solver := tracedSolver(flags)
+
+	h := &intcode.MemoryHeatmap{}
+	f := loadInput(day, test)
+	defer f.Close()
+	solver(f, h)
+
+	if *pngFile != "" {
+		out, err := os.Create(*pngFile)
+		if err != nil {
+			panic(fmt.Errorf("failed to create image: %w", err))
+		}
+		defer out.Close()
+
+		if err := h.WritePNG(out, *width, *scale); err != nil {
+			panic(fmt.Errorf("failed to write image: %w", err))
+		}
+		return
+	}
+
+	var k intcode.HeatmapKind
+	switch *kind {
+	case "reads":
+		k = intcode.HeatmapReads
+	case "writes":
+		k = intcode.HeatmapWrites
+	case "executes":
+		k = intcode.HeatmapExecutes
+	default:
+		usage()
+	}
+
+	if err := h.WriteGrid(os.Stdout, k, *width); err != nil {
+		panic(err)
+	}
+}
+
+// calls runs a solution while reconstructing the call stack of its program,
+// printing backtraces on breakpoints and panics, and instruction counts per routine
+func calls(args []string) {
+	flags := flag.NewFlagSet("calls", flag.ExitOnError)
+	breakpoints := flags.String("break", "", "comma-separated addresses, reaching which prints a backtrace")
+	folded := flags.String("folded", "", "save instruction counts per call path for flame graph tools")
+	flags.Parse(args)
+	day, test, solver := tracedSolver(flags)
+
+	breakAt := make(map[int]bool)
+	for _, addr := range strings.Split(*breakpoints, ",") {
+		if addr == "" {
+			continue
+		}
+
+		x, err := strconv.Atoi(addr)
+		if err != nil {
+			panic(fmt.Errorf("invalid breakpoint: %w", err))
+		}
+		breakAt[x] = true
+	}
+
+	c := intcode.NewCallStack()
+	c.W = os.Stderr
+	onBreak := intcode.TracerFunc(func(e intcode.TraceEntry) {
+		if breakAt[e.IP] {
+			fmt.Printf("breakpoint at %d, step %d\n", e.IP, e.Step)
+			c.WriteBacktrace(os.Stdout)
+		}
+	})
+
+	f := loadInput(day, test)
+	defer f.Close()
+	result := solver(f, intcode.Tracers{c, onBreak})
+	if result != nil {
+		fmt.Println(result)
+	}
+
+	fmt.Println("routine    calls  instructions")
+	for _, r := range c.Profile() {
+		fmt.Printf("%7d %8d %13d\n", r.Entry, r.Calls, r.Self)
+	}
+
+	if *folded != "" {
+		out, err := os.Create(*folded)
+		if err != nil {
+			panic(fmt.Errorf("failed to create folded stacks: %w", err))
+		}
+		defer out.Close()
+
+		if err := c.WriteFolded(out); err != nil {
+			panic(fmt.Errorf("failed to write folded stacks: %w", err))
+		}
+	}
+}
+
+var capturedSolutions = map[string]func(io.Reader, *day23.Capture) any{
+	"23a": day23.SolveACaptured,
+	"23b": day23.SolveBCaptured,
+}
+
+// netcap runs a day 23 solution, saving every packet sent through the network
+func netcap(args []string) {
+	flags := flag.NewFlagSet("netcap", flag.ExitOnError)
+	asCSV := flags.Bool("csv", false, "write CSV instead of JSON Lines")
+	flags.Parse(args)
+	if flags.NArg() != 2 && flags.NArg() != 3 {
+		usage()
+	}
+
+	day := flags.Arg(0)
+	test := flags.NArg() == 3 && flags.Arg(2) == "test"
+
+	solver, ok := capturedSolutions[day]
+	if !ok {
+		panic(fmt.Errorf("no captured solver for %q in main.go lookup table", day))
+	}
+
+	f := loadInput(day, test)
+	defer f.Close()
+
+	out, err := os.Create(flags.Arg(1))
+	if err != nil {
+		panic(fmt.Errorf("failed to create capture: %w", err))
+	}
+	defer out.Close()
+
+	format := day23.CaptureJSONLines
+	if *asCSV {
+		format = day23.CaptureCSV
+	}
+	c := day23.NewCapture(out, format)
+
+	result := solver(f, c)
+	if err := c.Flush(); err != nil {
+		panic(fmt.Errorf("failed to write capture: %w", err))
+	}
+	fmt.Println(result)
+}
+
+// netstats prints traffic statistics and the NAT history from a day 23 packet capture
+func netstats(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		panic(fmt.Errorf("failed to open capture: %w", err))
+	}
+	defer f.Close()
+
+	packets, err := day23.ReadCapture(f)
+	if err != nil {
+		panic(err)
+	}
+
+	day23.Analyze(packets, day23.NATAddress).WriteTo(os.Stdout)
+}
+
+// play starts an interactive session of a day's intcode program
+func play(args []string) {
+	if len(args) < 1 {
+		usage()
+	}
+
+	session, ok := playableDays[args[0]]
+	if !ok {
+		panic(fmt.Errorf("day %q can't be played", args[0]))
+	}
+	session(args[1:])
+}
+
+// rawTerminal switches stdin into raw mode, if it's a terminal.
+// The returned function restores the previous mode.
+func rawTerminal() (restore func()) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		panic(fmt.Errorf("failed to switch the terminal into raw mode: %w", err))
+	}
+	return func() { term.Restore(fd, state) }
+}
+
+// play13 plays the arcade game in the terminal
+func play13(args []string) {
+	flags := flag.NewFlagSet("play 13", flag.ExitOnError)
+	fps := flags.Float64("fps", 10, "frames per second, 0 for no limit")
+	autopilot := flags.Bool("autopilot", false, "start with the joystick driven by the autopilot")
+	transcript := flags.String("record", "", "save the game's intcode I/O into a transcript `file`")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	f := loadInput("13b", test)
+	i, err := intcode.NewInterpreterPatched(f, day13.FreePlay)
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+	i.Input = make(chan int)
+	i.Output = make(chan int)
+
+	var t intcode.Tracer
+	if *transcript != "" {
+		out, err := os.Create(*transcript)
+		if err != nil {
+			panic(fmt.Errorf("failed to create transcript: %w", err))
+		}
+		defer out.Close()
+		t = &intcode.Recorder{W: out}
+	}
+
+	p := &day13.Player{
+		Arcade:    day13.Arcade{Draw: i.Output, Joystick: i.Input, Halt: i.Halted},
+		W:         os.Stdout,
+		Keys:      day13.ReadKeys(os.Stdin),
+		Autopilot: *autopilot,
+	}
+	if *fps > 0 {
+		p.FrameDelay = time.Duration(float64(time.Second) / *fps)
+	}
+
+	restore := rawTerminal()
+	defer restore()
+
+	go i.ExecAllTraced(t)
+	finished := p.Play()
+	restore()
+
+	if finished {
+		fmt.Println("Game over! Score:", p.Score)
+	} else {
+		fmt.Println("Quit. Score:", p.Score)
+	}
+}
+
+// play25 plays the text adventure, running commands from a script first
+func play25(args []string) {
+	flags := flag.NewFlagSet("play 25", flag.ExitOnError)
+	script := flags.String("script", "", "run commands from this `file` before reading them from stdin")
+	transcript := flags.String("transcript", "", "save the whole session as text into this `file`")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	f := loadInput("25a", test)
+	s := day25.NewSession(intcode.NewSyncInterpreter(f), os.Stdout)
+	f.Close()
+
+	if *transcript != "" {
+		out, err := os.Create(*transcript)
+		if err != nil {
+			panic(fmt.Errorf("failed to create transcript: %w", err))
+		}
+		defer out.Close()
+		s.Transcript = out
+	}
+
+	var cmds io.ReadCloser
+	if *script != "" {
+		var err error
+		if cmds, err = os.Open(*script); err != nil {
+			panic(err)
+		}
+	}
+
+	s.Start()
+	if cmds != nil {
+		s.Script(cmds)
+		cmds.Close()
+	}
+	s.Interact(os.Stdin)
+}
+
+// arcade plays the day 13 game headlessly with every joystick strategy, comparing the results
+func arcade(args []string) {
+	flags := flag.NewFlagSet("arcade", flag.ExitOnError)
+	transcript := flags.String("replay", "", "also replay joystick inputs from a transcript `file`")
+	maxFrames := flags.Int("frames", 0, "stop every game after this many joystick inputs, 0 for no limit")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	f := loadInput("13b", test)
+	game, err := intcode.NewSyncInterpreterPatched(f, day13.FreePlay)
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	names := []string{"follow-ball", "predict"}
+	strategies := []day13.JoystickStrategy{day13.FollowBall{}, &day13.PredictTrajectory{}}
+
+	if *transcript != "" {
+		tf, err := os.Open(*transcript)
+		if err != nil {
+			panic(fmt.Errorf("failed to open transcript: %w", err))
+		}
+		t, err := intcode.ReadTranscript(tf)
+		tf.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		names = append(names, "replay")
+		strategies = append(strategies, day13.NewReplayedInputs(t))
+	}
+
+	fmt.Println("strategy       score  blocks  frames     steps  halted")
+	for idx, s := range strategies {
+		r := day13.PlayHeadless(game.Clone(), s, *maxFrames)
+		fmt.Printf("%-11s %8d %7d %7d %9d  %t\n", names[idx], r.Score, r.BlocksRemaining, r.Frames, r.Steps, r.Halted)
+	}
+}
+
+// maze maps out (or loads) the day 15 maze, and prints its statistics
+func maze(args []string) {
+	flags := flag.NewFlagSet("maze", flag.ExitOnError)
+	load := flags.String("load", "", "load the map from a `file` instead of exploring the maze")
+	save := flags.String("save", "", "save the map to a `file`")
+	asJSON := flags.Bool("json", false, "save the map as JSON instead of text")
+	show := flags.Bool("show", false, "print the map")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	var m map[day15.Point]day15.MapTile
+	var oxygen day15.Point
+	if *load != "" {
+		f, err := os.Open(*load)
+		if err != nil {
+			panic(fmt.Errorf("failed to open map: %w", err))
+		}
+		m, oxygen, err = day15.ReadMap(f)
+		f.Close()
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		f := loadInput("15", test)
+		m, oxygen = day15.GetMap(f)
+		f.Close()
+	}
+
+	if *save != "" {
+		out, err := os.Create(*save)
+		if err != nil {
+			panic(fmt.Errorf("failed to create map: %w", err))
+		}
+		if *asJSON {
+			err = day15.WriteMapJSON(m, out)
+		} else {
+			day15.ShowMap(m, out)
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			panic(fmt.Errorf("failed to save map: %w", err))
+		}
+	}
+
+	if *show {
+		day15.ShowMap(m, os.Stdout)
+	}
+
+	fromStart := day15.DistanceField(m, day15.Point{})
+	fromOxygen := day15.DistanceField(m, oxygen)
+	farthestFromStart, startDist := day15.Farthest(fromStart)
+	farthestFromOxygen, oxygenDist := day15.Farthest(fromOxygen)
+
+	fmt.Printf("reachable tiles:       %d\n", len(fromStart))
+	fmt.Printf("oxygen system:         %v, %d moves from the start\n", oxygen, fromStart[oxygen])
+	fmt.Printf("farthest from start:   %v, %d moves\n", farthestFromStart, startDist)
+	fmt.Printf("farthest from oxygen:  %v, %d moves\n", farthestFromOxygen, oxygenDist)
+	fmt.Printf("dead ends:             %d\n", len(day15.DeadEnds(m)))
+	fmt.Printf("articulation points:   %d\n", len(day15.ArticulationPoints(m)))
+}
+
+// video animates the continuous video feed of the day 17 vacuum robot
+func video(args []string) {
+	flags := flag.NewFlagSet("video", flag.ExitOnError)
+	fps := flags.Float64("fps", 30, "frames per second, 0 for no limit")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	f := loadInput("17b", test)
+	defer f.Close()
+
+	a := &day17.Animation{W: os.Stdout}
+	if *fps > 0 {
+		a.FrameDelay = time.Duration(float64(time.Second) / *fps)
+	}
+
+	d := &day17.FrameDecoder{}
+	day17.RunRobot(f, nil, true, func(c int) {
+		if frame, ok := d.Write(c); ok {
+			a.Draw(frame)
+		}
+	})
+	if frame, ok := d.Flush(); ok {
+		a.Draw(frame)
+	}
+
+	fmt.Printf("%d frames, collected %d dust\n", a.Frames, d.Dust)
+}
+
+// springscript compiles a boolean formula over the springdroid sensors into springscript,
+// optionally checking it offline against hulls from a file
+func springscript(args []string) {
+	flags := flag.NewFlagSet("springscript", flag.ExitOnError)
+	run := flags.Bool("run", false, "compile for RUN mode, with sensors up to I")
+	check := flags.String("check", "", "simulate the program on hulls from this file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+
+	p, err := day21.CompileString(flags.Arg(0), *run)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(p)
+	if !p.Minimal {
+		fmt.Fprintln(os.Stderr, "note: a shorter program might exist")
+	}
+
+	if *check != "" {
+		failed := 0
+		for _, h := range readHulls(*check) {
+			if o := p.Simulate(h); !o.Survived {
+				fmt.Printf("\nFell at tile %d:\n%s\n", o.Fell, o.Draw(h))
+				failed++
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+func readHulls(name string) []day21.Hull {
+	f, err := os.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	hulls, err := day21.ReadHulls(f)
+	if err != nil {
+		panic(err)
+	}
+	return hulls
+}
+
+// springsearch learns a springscript program from hulls the springdroid falls into.
+// Learned hulls are appended to the -hulls file. With -offline, the intcode program isn't run,
+// and only a candidate crossing the hulls from the file is printed.
+func springsearch(args []string) {
+	flags := flag.NewFlagSet("springsearch", flag.ExitOnError)
+	run := flags.Bool("run", false, "search for a RUN mode program, with sensors up to I")
+	hullsFile := flags.String("hulls", "", "file with hulls which the program must cross")
+	rounds := flags.Int("rounds", day21.MaxRounds, "maximum amount of programs sent to the springdroid")
+	offline := flags.Bool("offline", false, "only find a candidate crossing the hulls from the file")
+	flags.Parse(args)
+	test := flags.NArg() == 1 && flags.Arg(0) == "test"
+
+	s := &day21.Search{Run: *run}
+	if *hullsFile != "" {
+		if _, err := os.Stat(*hullsFile); err == nil {
+			s.Hulls = readHulls(*hullsFile)
+		}
+	}
+	known := len(s.Hulls)
+
+	var p day21.Program
+	var formula day21.Formula
+	var damage int
+	var err error
+	if *offline {
+		p, formula, err = s.Candidate()
+	} else {
+		day := "21a"
+		if *run {
+			day = "21b"
+		}
+		f := loadInput(day, test)
+		p, formula, damage, err = s.Learn(day21.Droid{M: intcode.NewSyncInterpreter(f)}, *rounds)
+		f.Close()
+	}
+
+	for _, h := range s.Hulls[known:] {
+		fmt.Printf("Learned hull: %s\n", h)
+	}
+
+	if *hullsFile != "" && len(s.Hulls) > known {
+		f, err := os.OpenFile(*hullsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			panic(err)
+		}
+		if err := day21.WriteHulls(f, s.Hulls[known:]); err != nil {
+			panic(err)
+		}
+		f.Close()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("J = %s\n%s", formula, p)
+	if !*offline {
+		fmt.Printf("Hull damage: %d\n", damage)
+	}
+}
+
 func main() {
 	// Parse arguments
-	if len(os.Args) != 2 && len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s DAY-NUMBER [test]\n", os.Args[0])
-		os.Exit(1)
+	flag.Var(&userPatches, "patch", "patch the intcode program: ADDR=VALUE[,...], FILE or FILE:NAME")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+
+	// User patches are applied to the input, before the solutions apply their own patches,
+	// which mustn't override or reject them
+	for _, p := range solutionPatches {
+		*p = p.Except(userPatches...)
+	}
+
+	if len(args) < 1 {
+		usage()
+	}
+
+	if cmd, ok := commands[args[0]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if len(args) != 1 && len(args) != 2 {
+		usage()
 	}
 
-	day := os.Args[1]
+	day := args[0]
 	// Enable test data?
-	test := len(os.Args) == 3 && os.Args[2] == "test"
+	test := len(args) == 2 && args[1] == "test"
 
 	// Open the input file
 	f := loadInput(day, test)
@@ -134,6 +1161,9 @@ func main() {
 
 	// Get the solver function
 	solver, ok := solutions[day]
+	if !ok {
+		solver, ok = alternativeSolutions[day]
+	}
 	if !ok {
 		panic(fmt.Errorf("no solver for %q in main.go lookup table", day))
 	}